@@ -0,0 +1,253 @@
+package copperhead
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// WithAliases enables field aliases. Aliases are declared as a comma
+// separated list in an "alias" struct tag, and let fields be
+// addressed using old names:
+//
+//	Port int `alias:"OldPort,LegacyPort"`
+//
+// Aliases are used when resolving field names, and as fallback keys
+// when decoding configuration data. Alias keys are decoded using
+// "json" and "yaml" struct tags, so they work with JSON and YAML
+// style unmarshalers. Values from alias keys are applied before the
+// regular keys, so a regular key takes precedence over its aliases.
+// Aliases that are ambiguous cause an error.
+func WithAliases() Option {
+	return func(c *Config) error {
+		if err := c.requireStruct("aliases"); err != nil {
+			return err
+		}
+
+		err := checkAliases(c.obj.Type(), aliasTagKey, map[reflect.Type]bool{})
+		if err != nil {
+			return err
+		}
+
+		c.aliases = true
+
+		return nil
+	}
+}
+
+// aliasTagKey is the struct tag key that aliases are declared with.
+const aliasTagKey = "alias"
+
+// fieldAliases returns the aliases in the key tag of a field.
+func fieldAliases(key string, f reflect.StructField) []string {
+	value := f.Tag.Get(key)
+	if value == "" {
+		return nil
+	}
+
+	var aliases []string
+	for _, a := range strings.Split(value, ",") {
+		if a = strings.TrimSpace(a); a != "" {
+			aliases = append(aliases, a)
+		}
+	}
+
+	return aliases
+}
+
+// checkAliases verifies that no alias collides with a field name or
+// another alias.
+func checkAliases(t reflect.Type, key string, seen map[reflect.Type]bool) error {
+	if seen[t] {
+		return nil
+	}
+	seen[t] = true
+
+	names := make(map[string]string)
+	for i := 0; i < t.NumField(); i++ {
+		names[t.Field(i).Name] = t.Field(i).Name
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		for _, alias := range fieldAliases(key, f) {
+			if owner, ok := names[alias]; ok {
+				return errors.Errorf(
+					"the alias %q of %s.%s is ambiguous, it's already used by %q",
+					alias, t.Name(), f.Name, owner,
+				)
+			}
+			names[alias] = f.Name
+		}
+
+		ft := f.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+
+		if f.PkgPath == "" && isMergeable(ft) {
+			if err := checkAliases(ft, key, seen); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// findField finds a field by name, falling back to the aliases in
+// the aliasTag tag if it's set.
+func findField(t reflect.Type, name string, aliasTag string) (reflect.StructField, bool) {
+	sf, ok := t.FieldByName(name)
+	if ok || aliasTag == "" {
+		return sf, ok
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		for _, alias := range fieldAliases(aliasTag, t.Field(i)) {
+			if alias == name {
+				return t.Field(i), true
+			}
+		}
+	}
+
+	return sf, false
+}
+
+// aliasShadow describes a struct type that mirrors the aliased
+// fields of a configuration struct, so that alias keys can be
+// decoded using the same unmarshaler.
+type aliasShadow struct {
+	typ    reflect.Type
+	fields []aliasField
+}
+
+type aliasField struct {
+	shadowIndex int
+	realIndex   int
+	nested      *aliasShadow
+}
+
+// buildAliasShadow creates the alias shadow of t, returns nil if t
+// doesn't have any aliased fields.
+func buildAliasShadow(t reflect.Type, key string, seen map[reflect.Type]bool) *aliasShadow {
+	if seen[t] {
+		return nil
+	}
+	seen[t] = true
+	defer delete(seen, t)
+
+	var (
+		fields []reflect.StructField
+		shadow aliasShadow
+	)
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+
+		for _, alias := range fieldAliases(key, f) {
+			shadow.fields = append(shadow.fields, aliasField{
+				shadowIndex: len(fields),
+				realIndex:   i,
+			})
+			// Aliases are decoded into pointers so that
+			// explicit zero values can be told apart from
+			// missing keys.
+			fields = append(fields, reflect.StructField{
+				Name: "Alias" + strconv.Itoa(len(fields)),
+				Type: reflect.PtrTo(f.Type),
+				Tag: reflect.StructTag(`json:"` + alias +
+					`" yaml:"` + strings.ToLower(alias) + `"`),
+			})
+		}
+
+		ft := f.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+
+		if !isMergeable(ft) {
+			continue
+		}
+
+		nested := buildAliasShadow(ft, key, seen)
+		if nested == nil {
+			continue
+		}
+
+		nt := nested.typ
+		if f.Type.Kind() == reflect.Ptr {
+			nt = reflect.PtrTo(nt)
+		}
+
+		shadow.fields = append(shadow.fields, aliasField{
+			shadowIndex: len(fields),
+			realIndex:   i,
+			nested:      nested,
+		})
+		fields = append(fields, reflect.StructField{
+			Name: f.Name,
+			Type: nt,
+			Tag:  f.Tag,
+		})
+	}
+
+	if len(fields) == 0 {
+		return nil
+	}
+
+	shadow.typ = reflect.StructOf(fields)
+
+	return &shadow
+}
+
+// apply copies the alias values that are present in the shadow value
+// src to the configuration value dst.
+func (as *aliasShadow) apply(dst, src reflect.Value) {
+	for _, af := range as.fields {
+		sv := src.Field(af.shadowIndex)
+		if sv.IsZero() {
+			continue
+		}
+
+		dv := dst.Field(af.realIndex)
+
+		if af.nested == nil {
+			dv.Set(sv.Elem())
+			continue
+		}
+
+		if sv.Kind() == reflect.Ptr {
+			if dv.IsNil() {
+				dv.Set(reflect.New(dv.Type().Elem()))
+			}
+			sv, dv = sv.Elem(), dv.Elem()
+		}
+
+		af.nested.apply(dv, sv)
+	}
+}
+
+// unmarshalAliases decodes data into the alias shadow of the
+// configuration and applies the alias values.
+func (c *Config) unmarshalAliases(name string, data []byte, unm Unmarshaler) error {
+	shadow := buildAliasShadow(c.obj.Type(), aliasTagKey, map[reflect.Type]bool{})
+	if shadow == nil {
+		return nil
+	}
+
+	sv := reflect.New(shadow.typ)
+	if err := unmarshalInto(name, data, unm, sv.Interface()); err != nil {
+		return errors.Wrap(err, "failed to unmarshal aliases")
+	}
+
+	shadow.apply(c.obj, sv.Elem())
+
+	return nil
+}
@@ -0,0 +1,172 @@
+package copperhead
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// keyShadow describes a struct type that mirrors a configuration
+// struct with pointer fields, so that decoding configuration data
+// into it tells which fields the data assigns, even when it assigns
+// the zero value or the value that a field already has.
+type keyShadow struct {
+	typ    reflect.Type
+	fields []keyField
+}
+
+type keyField struct {
+	path   string
+	nested *keyShadow
+}
+
+// buildKeyShadow creates the key shadow of t. The fields are mirrored
+// in the same way as walkFields lists them, and aliases in the
+// aliasTag tag get fields of their own if it's set.
+func buildKeyShadow(t reflect.Type, prefix, aliasTag string, seen map[reflect.Type]bool) *keyShadow {
+	seen[t] = true
+	defer delete(seen, t)
+
+	var (
+		fields []reflect.StructField
+		shadow keyShadow
+		names  = make(map[string]bool, t.NumField())
+	)
+
+	for i := 0; i < t.NumField(); i++ {
+		names[t.Field(i).Name] = true
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		ft := f.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+
+		embedded := f.Anonymous && isMergeable(ft) && !seen[ft] &&
+			(f.PkgPath == "" || f.Type.Kind() != reflect.Ptr)
+
+		if f.PkgPath != "" && !embedded {
+			continue
+		}
+
+		name := f.Name
+		if f.PkgPath != "" {
+			name = exportedName(name, names)
+		}
+
+		var kf keyField
+
+		sf := reflect.StructField{
+			Name:      name,
+			Type:      f.Type,
+			Tag:       f.Tag,
+			Anonymous: embedded,
+		}
+
+		// Values that can't be nil are decoded into pointers, so
+		// that keys with zero values are present.
+		if !canBeNil(f.Type) {
+			sf.Type = reflect.PtrTo(f.Type)
+		}
+
+		if embedded || (isMergeable(ft) && !seen[ft]) {
+			nestedPrefix := prefix
+			if !embedded {
+				nestedPrefix = prefix + f.Name + "."
+			}
+
+			kf.nested = buildKeyShadow(ft, nestedPrefix, aliasTag, seen)
+
+			sf.Type = kf.nested.typ
+			if f.Type.Kind() == reflect.Ptr {
+				sf.Type = reflect.PtrTo(sf.Type)
+			}
+		} else {
+			kf.path = prefix + f.Name
+		}
+
+		shadow.fields = append(shadow.fields, kf)
+		fields = append(fields, sf)
+
+		if aliasTag == "" {
+			continue
+		}
+
+		for _, alias := range fieldAliases(aliasTag, f) {
+			shadow.fields = append(shadow.fields, kf)
+			fields = append(fields, reflect.StructField{
+				Name: exportedName("Alias"+strconv.Itoa(len(fields)), names),
+				Type: sf.Type,
+				Tag: reflect.StructTag(`json:"` + alias +
+					`" yaml:"` + strings.ToLower(alias) + `"`),
+			})
+		}
+	}
+
+	shadow.typ = reflect.StructOf(fields)
+
+	return &shadow
+}
+
+// paths calls fn with the path of every field that is present in the
+// shadow value v.
+func (ks *keyShadow) paths(v reflect.Value, fn func(path string)) {
+	for i, kf := range ks.fields {
+		fv := v.Field(i)
+		if canBeNil(fv.Type()) && fv.IsNil() {
+			continue
+		}
+
+		if kf.nested != nil {
+			kf.nested.paths(reflect.Indirect(fv), fn)
+			continue
+		}
+
+		fn(kf.path)
+	}
+}
+
+// canBeNil checks if values of the type t can be nil.
+func canBeNil(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Map, reflect.Interface:
+		return true
+	}
+	return false
+}
+
+// assignedPaths decodes data into the key shadow of the configuration
+// and returns the paths of the fields that the data assigns. The
+// returned bool is false if the keys couldn't be decoded, which can
+// happen with types that only unmarshal in a certain context.
+func (c *Config) assignedPaths(name string, data []byte, unm Unmarshaler) ([]string, bool) {
+	if c.obj.Kind() != reflect.Struct {
+		return nil, false
+	}
+
+	shadow := buildKeyShadow(c.obj.Type(), "", c.aliasTag(),
+		map[reflect.Type]bool{})
+
+	sv := reflect.New(shadow.typ)
+	if err := unmarshalInto(name, data, unm, sv.Interface()); err != nil {
+		return nil, false
+	}
+
+	var paths []string
+
+	// A field can be assigned by both its regular key and an
+	// alias.
+	seen := make(map[string]bool)
+
+	shadow.paths(sv.Elem(), func(path string) {
+		if !seen[path] {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	})
+
+	return paths, true
+}
@@ -0,0 +1,199 @@
+package copperhead
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+// WithAutoEnv binds every exported field to an environment variable
+// with the prefix and a name derived from the fields dotted path. Use
+// UpperSnake, UpperFlat, or a custom function as transform.
+func WithAutoEnv(prefix string, transform func(path string) string) Option {
+	return func(c *Config) error {
+		return c.AutoEnv(prefix, transform)
+	}
+}
+
+// UpperSnake transforms a dotted field path into an upper case
+// environment variable name where the words of camel case names are
+// separated by underscores, so "Birdie.ComplexEnv" becomes
+// "BIRDIE_COMPLEX_ENV" and "YamlIT" becomes "YAML_IT".
+func UpperSnake(path string) string {
+	var words []string
+	for _, name := range strings.Split(path, ".") {
+		words = append(words, splitCamelCase(name)...)
+	}
+	return strings.ToUpper(strings.Join(words, "_"))
+}
+
+// UpperFlat transforms a dotted field path into an upper case
+// environment variable name without splitting camel case names, so
+// "Birdie.ComplexEnv" becomes "BIRDIE_COMPLEXENV".
+func UpperFlat(path string) string {
+	return strings.ToUpper(strings.Replace(path, ".", "_", -1))
+}
+
+// splitCamelCase splits a camel case name into words. Runs of upper
+// case letters are treated as acronyms, so "HTTPServer" becomes
+// "HTTP" and "Server". Digits belong to the preceding word, and
+// underscores separate words.
+func splitCamelCase(name string) []string {
+	var (
+		words []string
+		word  []rune
+	)
+
+	runes := []rune(name)
+	for i, r := range runes {
+		if r == '_' {
+			if len(word) > 0 {
+				words = append(words, string(word))
+			}
+			word = nil
+			continue
+		}
+
+		if len(word) > 0 && unicode.IsUpper(r) {
+			prev := word[len(word)-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+
+			// Start a new word at a lower to upper case
+			// transition, or at the last upper case letter of
+			// an acronym that's followed by a lower case
+			// letter.
+			if !unicode.IsUpper(prev) || nextLower {
+				words = append(words, string(word))
+				word = nil
+			}
+		}
+
+		word = append(word, r)
+	}
+
+	if len(word) > 0 {
+		words = append(words, string(word))
+	}
+
+	return words
+}
+
+// AutoEnv populates every exported field from an environment
+// variable named by the prefix followed by a name derived from the
+// dotted path of the field using transform, defaults to UpperSnake.
+// So with the prefix "APP_" the field "Birdie.Name" is read from
+// "APP_BIRDIE_NAME". The prefix is required, so that fields like
+// "Path" and "User" aren't read from unrelated variables. Fields are
+// left untouched if their environment variable is unset.
+func (c *Config) AutoEnv(prefix string, transform func(path string) string) error {
+	if err := c.requireStruct("automatic environment mapping"); err != nil {
+		return err
+	}
+
+	if prefix == "" {
+		return errors.New("the prefix cannot be empty")
+	}
+
+	if transform == nil {
+		transform = UpperSnake
+	}
+
+	return c.bindEnv("", c.obj.Type(), func(path string) string {
+		return prefix + transform(path)
+	})
+}
+
+// WithEnvironmentSubtree binds every exported field of the nested
+// struct at path to an environment variable, see
+// Config.EnvironmentSubtree.
+func WithEnvironmentSubtree(path, prefix string) Option {
+	return func(c *Config) error {
+		return c.EnvironmentSubtree(path, prefix)
+	}
+}
+
+// EnvironmentSubtree populates every exported field of the nested
+// struct at path from an environment variable named by the prefix
+// followed by the UpperSnake version of the fields path relative to
+// the subtree. So with the prefix "BIRD_" the field "Birdie.Name" is
+// read from "BIRD_NAME". Fields are left untouched if their
+// environment variable is unset.
+func (c *Config) EnvironmentSubtree(path, prefix string) error {
+	f, ok := fieldByPath(c.obj.Type(), path, c.aliasTag())
+	if !ok {
+		return errors.Errorf("unknown configuration field %q", path)
+	}
+
+	t := baseType(f.Type)
+	if t.Kind() != reflect.Struct {
+		return errors.Errorf(
+			"%q is a %q, not a struct", path, t.Kind().String())
+	}
+
+	return c.bindEnv(path+".", t, func(rel string) string {
+		return prefix + UpperSnake(rel)
+	})
+}
+
+// bindEnv assigns the fields of the struct type t, found at prefix,
+// from the environment variables named by envName.
+func (c *Config) bindEnv(
+	prefix string, t reflect.Type, envName func(rel string) string,
+) error {
+	if err := c.checkFrozen(); err != nil {
+		return err
+	}
+
+	var err error
+
+	walkFields(t, func(rel string, _ reflect.StructField) {
+		if err != nil {
+			return
+		}
+
+		path := prefix + rel
+		name := envName(rel)
+		c.envVars[path] = []string{name}
+
+		eVal, ok := os.LookupEnv(name)
+		if !ok {
+			return
+		}
+
+		if c.envTransform != nil {
+			eVal = c.envTransform(path, eVal)
+		}
+
+		c.logf("assigning %s from %q: %s",
+			path, name, c.logValue(path, eVal))
+
+		src := Source{Type: SourceEnv, Name: name}
+
+		if ok, sErr := c.CheckSource(path, src); !ok {
+			err = sErr
+			return
+		}
+
+		v, rErr := c.resolve(path)
+		if rErr != nil {
+			err = errors.Wrapf(rErr,
+				"could not resolve %q", path)
+			return
+		}
+
+		if aErr := c.assignField(path, v, eVal); aErr != nil {
+			err = errors.Wrapf(aErr,
+				"could not assign the value of %q to %q",
+				name, path,
+			)
+			return
+		}
+
+		c.record(path, src)
+	})
+
+	return err
+}
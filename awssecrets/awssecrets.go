@@ -0,0 +1,229 @@
+// Package awssecrets loads configuration from AWS Secrets Manager.
+//
+// The package doesn't depend on the AWS SDK, instead the SDK client
+// is adapted to the Client interface, which makes it possible to use
+// either version of the SDK:
+//
+//	type smClient struct {
+//		sm *secretsmanager.Client
+//	}
+//
+//	func (c smClient) GetSecretValue(
+//		ctx context.Context, secretID string,
+//	) (*awssecrets.SecretValue, error) {
+//		out, err := c.sm.GetSecretValue(ctx,
+//			&secretsmanager.GetSecretValueInput{
+//				SecretId: aws.String(secretID),
+//			})
+//		if err != nil {
+//			return nil, err
+//		}
+//		return &awssecrets.SecretValue{
+//			String: out.SecretString,
+//			Binary: out.SecretBinary,
+//		}, nil
+//	}
+package awssecrets
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/Sydsvenskan/copperhead"
+	"github.com/pkg/errors"
+)
+
+// Client fetches secret values.
+type Client interface {
+	GetSecretValue(ctx context.Context, secretID string) (*SecretValue, error)
+}
+
+// SecretValue is a secret payload, either String or Binary will be
+// set.
+type SecretValue struct {
+	String *string
+	Binary []byte
+}
+
+// The kinds of errors that can occur when a secret is loaded, use
+// errors.Is() to check the kind of an error.
+var (
+	ErrNotFound     = errors.New("secret not found")
+	ErrAccessDenied = errors.New("access denied to secret")
+	ErrDecode       = errors.New("failed to decode secret")
+)
+
+// Error is returned when a secret can't be loaded.
+type Error struct {
+	SecretID string
+	// Kind is ErrNotFound, ErrAccessDenied, ErrDecode, or nil
+	// for other errors.
+	Kind error
+	Err  error
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	msg := "failed to load secret " + `"` + e.SecretID + `"`
+	if e.Kind != nil {
+		msg += ": " + e.Kind.Error()
+	}
+	return msg + ": " + e.Err.Error()
+}
+
+// Is reports whether target is the kind of the error.
+func (e *Error) Is(target error) bool {
+	return e.Kind != nil && target == e.Kind
+}
+
+// Cause returns the underlying error.
+func (e *Error) Cause() error {
+	return e.Err
+}
+
+// WithSecret reads configuration from a secret containing a JSON
+// blob.
+func WithSecret(ctx context.Context, client Client, secretID string) copperhead.Option {
+	return func(c *copperhead.Config) error {
+		return Secret(ctx, c, client, secretID)
+	}
+}
+
+// WithSecretKeys assigns keys from a secret containing a JSON
+// object to fields, the keys map is keyed by field name.
+func WithSecretKeys(
+	ctx context.Context, client Client, secretID string,
+	keys map[string]string,
+) copperhead.Option {
+	return func(c *copperhead.Config) error {
+		return SecretKeys(ctx, c, client, secretID, keys)
+	}
+}
+
+// Secret reads configuration from a secret containing a JSON blob.
+func Secret(
+	ctx context.Context, c *copperhead.Config,
+	client Client, secretID string,
+) error {
+	if c.Preflight() {
+		return nil
+	}
+
+	data, err := fetch(ctx, client, secretID)
+	if err != nil {
+		return err
+	}
+
+	if err := c.Data(data, nil); err != nil {
+		return &Error{SecretID: secretID, Kind: ErrDecode, Err: err}
+	}
+
+	return nil
+}
+
+// SecretKeys assigns keys from a secret containing a JSON object to
+// fields, the keys map is keyed by field name. String values are
+// assigned as-is and other values are assigned as JSON. Keys that
+// are missing in the secret leave their fields untouched.
+func SecretKeys(
+	ctx context.Context, c *copperhead.Config,
+	client Client, secretID string, keys map[string]string,
+) error {
+	if c.Preflight() {
+		return nil
+	}
+
+	data, err := fetch(ctx, client, secretID)
+	if err != nil {
+		return err
+	}
+
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(data, &values); err != nil {
+		return &Error{SecretID: secretID, Kind: ErrDecode, Err: err}
+	}
+
+	for name, key := range keys {
+		raw, ok := values[key]
+		if !ok {
+			continue
+		}
+
+		value := string(raw)
+
+		var str string
+		if err := json.Unmarshal(raw, &str); err == nil {
+			value = str
+		}
+
+		if err := c.Set(name, value); err != nil {
+			return errors.Wrapf(err,
+				"failed to assign secret key %q", key)
+		}
+	}
+
+	return nil
+}
+
+func fetch(ctx context.Context, client Client, secretID string) ([]byte, error) {
+	sv, err := client.GetSecretValue(ctx, secretID)
+	if err != nil {
+		return nil, &Error{
+			SecretID: secretID,
+			Kind:     classify(err),
+			Err:      err,
+		}
+	}
+
+	switch {
+	case sv == nil:
+		return nil, &Error{
+			SecretID: secretID, Kind: ErrDecode,
+			Err: errors.New("no secret value returned"),
+		}
+	case sv.String != nil:
+		return []byte(*sv.String), nil
+	case sv.Binary != nil:
+		return sv.Binary, nil
+	}
+
+	return nil, &Error{
+		SecretID: secretID, Kind: ErrDecode,
+		Err: errors.New("the secret has no payload"),
+	}
+}
+
+// classify maps AWS error codes to our error kinds. Both the v1
+// and v2 SDK error code interfaces are supported.
+func classify(err error) error {
+	for err != nil {
+		var code string
+
+		switch e := err.(type) {
+		case interface{ ErrorCode() string }:
+			code = e.ErrorCode()
+		case interface{ Code() string }:
+			code = e.Code()
+		}
+
+		switch code {
+		case "ResourceNotFoundException":
+			return ErrNotFound
+		case "AccessDeniedException":
+			return ErrAccessDenied
+		case "DecryptionFailure", "DecryptionFailureException":
+			return ErrDecode
+		}
+
+		switch e := err.(type) {
+		case interface{ Unwrap() error }:
+			err = e.Unwrap()
+		case interface{ Cause() error }:
+			err = e.Cause()
+		default:
+			err = nil
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,119 @@
+package awssecrets_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Sydsvenskan/copperhead"
+	"github.com/Sydsvenskan/copperhead/awssecrets"
+)
+
+type apiError struct {
+	code string
+}
+
+func (e apiError) Error() string     { return "api error: " + e.code }
+func (e apiError) ErrorCode() string { return e.code }
+
+type fakeClient map[string]*awssecrets.SecretValue
+
+func (fc fakeClient) GetSecretValue(
+	ctx context.Context, secretID string,
+) (*awssecrets.SecretValue, error) {
+	switch secretID {
+	case "denied":
+		return nil, apiError{code: "AccessDeniedException"}
+	}
+
+	sv, ok := fc[secretID]
+	if !ok {
+		return nil, apiError{code: "ResourceNotFoundException"}
+	}
+	return sv, nil
+}
+
+type dbConf struct {
+	User     string
+	Password string
+	Port     int
+}
+
+func str(s string) *string {
+	return &s
+}
+
+func TestSecret(t *testing.T) {
+	client := fakeClient{
+		"blob": &awssecrets.SecretValue{
+			String: str(`{"User":"admin","Password":"hunter2"}`),
+		},
+		"binary": &awssecrets.SecretValue{
+			Binary: []byte(`{"Port":5432}`),
+		},
+	}
+
+	var conf dbConf
+	err := copperhead.Configure(&conf,
+		awssecrets.WithSecret(context.Background(), client, "blob"),
+		awssecrets.WithSecret(context.Background(), client, "binary"),
+	)
+	if err != nil {
+		t.Error("failed to load secrets: " + err.Error())
+		return
+	}
+
+	if conf.User != "admin" || conf.Password != "hunter2" || conf.Port != 5432 {
+		t.Errorf("unexpected configuration %#v", conf)
+	}
+}
+
+func TestSecretKeys(t *testing.T) {
+	client := fakeClient{
+		"db": &awssecrets.SecretValue{
+			String: str(`{"username":"admin","port":5432}`),
+		},
+	}
+
+	var conf dbConf
+	err := copperhead.Configure(&conf,
+		awssecrets.WithSecretKeys(context.Background(), client, "db",
+			map[string]string{
+				"User":     "username",
+				"Port":     "port",
+				"Password": "password",
+			}),
+	)
+	if err != nil {
+		t.Error("failed to load secrets: " + err.Error())
+		return
+	}
+
+	if conf.User != "admin" || conf.Port != 5432 || conf.Password != "" {
+		t.Errorf("unexpected configuration %#v", conf)
+	}
+}
+
+func TestSecretErrors(t *testing.T) {
+	client := fakeClient{
+		"garbage": &awssecrets.SecretValue{
+			String: str(`not json`),
+		},
+	}
+
+	cases := map[string]error{
+		"missing": awssecrets.ErrNotFound,
+		"denied":  awssecrets.ErrAccessDenied,
+		"garbage": awssecrets.ErrDecode,
+	}
+
+	for secretID, kind := range cases {
+		err := copperhead.Configure(&dbConf{},
+			awssecrets.WithSecret(context.Background(), client, secretID),
+		)
+		if !errors.Is(err, kind) {
+			t.Errorf("expected %q to fail with %q, got: %v",
+				secretID, kind, err)
+		}
+	}
+}
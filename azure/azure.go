@@ -0,0 +1,279 @@
+// Package azure loads configuration from Azure App Configuration and
+// Azure Key Vault.
+//
+// The package doesn't depend on the Azure SDK, instead the SDK
+// clients are adapted to the AppConfigClient and KeyVaultClient
+// interfaces:
+//
+//	type kvClient struct {
+//		kv *azsecrets.Client
+//	}
+//
+//	func (c kvClient) ListSecretNames(ctx context.Context) ([]string, error) {
+//		var names []string
+//		pager := c.kv.NewListSecretPropertiesPager(nil)
+//		for pager.More() {
+//			page, err := pager.NextPage(ctx)
+//			if err != nil {
+//				return nil, err
+//			}
+//			for _, s := range page.Value {
+//				names = append(names, s.ID.Name())
+//			}
+//		}
+//		return names, nil
+//	}
+//
+//	func (c kvClient) GetSecret(ctx context.Context, name string) (string, error) {
+//		resp, err := c.kv.GetSecret(ctx, name, "", nil)
+//		if err != nil {
+//			return "", err
+//		}
+//		return *resp.Value, nil
+//	}
+//
+// Errors from the SDK, like *azcore.ResponseError, are classified
+// using their HTTP status code.
+package azure
+
+import (
+	"context"
+	"reflect"
+	"strings"
+
+	"github.com/Sydsvenskan/copperhead"
+	"github.com/pkg/errors"
+)
+
+// AppConfigClient lists key-values from App Configuration.
+type AppConfigClient interface {
+	ListSettings(ctx context.Context, labelFilter string) ([]Setting, error)
+}
+
+// Setting is an App Configuration key-value.
+type Setting struct {
+	Key   string
+	Label string
+	Value *string
+}
+
+// KeyVaultClient lists and reads secrets from Key Vault.
+type KeyVaultClient interface {
+	ListSecretNames(ctx context.Context) ([]string, error)
+	GetSecret(ctx context.Context, name string) (string, error)
+}
+
+// The kinds of errors that can occur when values are fetched, use
+// errors.Is() to check the kind of an error.
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrUnauthorized = errors.New("unauthorized")
+)
+
+// Error is returned when configuration can't be fetched from Azure.
+type Error struct {
+	// Source is "app configuration" or "key vault".
+	Source string
+	// Name is the secret name, if any.
+	Name string
+	// Kind is ErrNotFound, ErrUnauthorized, or nil for other
+	// errors.
+	Kind error
+	Err  error
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	msg := "failed to fetch configuration from " + e.Source
+	if e.Name != "" {
+		msg += ` "` + e.Name + `"`
+	}
+	if e.Kind != nil {
+		msg += ": " + e.Kind.Error()
+	}
+	return msg + ": " + e.Err.Error()
+}
+
+// Is reports whether target is the kind of the error.
+func (e *Error) Is(target error) bool {
+	return e.Kind != nil && target == e.Kind
+}
+
+// Cause returns the underlying error.
+func (e *Error) Cause() error {
+	return e.Err
+}
+
+// WithAppConfig assigns key-values from App Configuration to fields,
+// see AppConfig.
+func WithAppConfig(client AppConfigClient, labelFilter string) copperhead.Option {
+	return func(c *copperhead.Config) error {
+		return AppConfig(c.Context(), c, client, labelFilter)
+	}
+}
+
+// WithKeyVault assigns secrets from Key Vault to fields, see
+// KeyVault.
+func WithKeyVault(client KeyVaultClient, prefix string) copperhead.Option {
+	return func(c *copperhead.Config) error {
+		return KeyVault(c.Context(), c, client, prefix)
+	}
+}
+
+// AppConfig assigns the key-values matching the label filter to
+// fields. Keys are dotted field paths, where ":" and "/" can be used
+// as separators as well, and are matched case-insensitively, so the
+// key "birdie:value" is assigned to "Birdie.Value". Keys that don't
+// match a field are ignored.
+func AppConfig(
+	ctx context.Context, c *copperhead.Config,
+	client AppConfigClient, labelFilter string,
+) error {
+	if c.Preflight() {
+		return nil
+	}
+
+	settings, err := client.ListSettings(ctx, labelFilter)
+	if err != nil {
+		return &Error{
+			Source: "app configuration",
+			Kind:   classify(err),
+			Err:    err,
+		}
+	}
+
+	paths := fieldPaths(c)
+
+	for _, s := range settings {
+		if s.Value == nil {
+			continue
+		}
+
+		key := strings.NewReplacer(":", ".", "/", ".").Replace(s.Key)
+
+		path, ok := paths[strings.ToLower(key)]
+		if !ok {
+			continue
+		}
+
+		if err := c.Set(path, *s.Value); err != nil {
+			return errors.Wrapf(err,
+				"failed to assign the key %q", s.Key)
+		}
+	}
+
+	return nil
+}
+
+// KeyVault assigns the secrets with names starting with prefix to
+// fields. Secret names can only contain alphanumerics and dashes,
+// so "--" is used as the field separator and the name
+// "myapp-Database--Password" with the prefix "myapp-" is assigned to
+// "Database.Password". Names are matched case-insensitively, and
+// secrets that don't match a field are ignored.
+func KeyVault(
+	ctx context.Context, c *copperhead.Config,
+	client KeyVaultClient, prefix string,
+) error {
+	if c.Preflight() {
+		return nil
+	}
+
+	names, err := client.ListSecretNames(ctx)
+	if err != nil {
+		return &Error{
+			Source: "key vault",
+			Kind:   classify(err),
+			Err:    err,
+		}
+	}
+
+	paths := fieldPaths(c)
+
+	for _, name := range names {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+
+		key := strings.Replace(name[len(prefix):], "--", ".", -1)
+
+		path, ok := paths[strings.ToLower(key)]
+		if !ok {
+			continue
+		}
+
+		value, err := client.GetSecret(ctx, name)
+		if err != nil {
+			return &Error{
+				Source: "key vault",
+				Name:   name,
+				Kind:   classify(err),
+				Err:    err,
+			}
+		}
+
+		if err := c.Set(path, value); err != nil {
+			return errors.Wrapf(err,
+				"failed to assign the secret %q", name)
+		}
+	}
+
+	return nil
+}
+
+// fieldPaths maps the lowercased paths of the configuration fields
+// to the paths.
+func fieldPaths(c *copperhead.Config) map[string]string {
+	paths := make(map[string]string)
+	for _, f := range c.Fields() {
+		paths[strings.ToLower(f.Path)] = f.Path
+	}
+	return paths
+}
+
+// classify maps HTTP status codes to our error kinds. The status code
+// is read from a StatusCode field, as in *azcore.ResponseError, or a
+// StatusCode() method.
+func classify(err error) error {
+	for err != nil {
+		if err == ErrNotFound || err == ErrUnauthorized {
+			return err
+		}
+
+		switch statusCode(err) {
+		case 404:
+			return ErrNotFound
+		case 401, 403:
+			return ErrUnauthorized
+		}
+
+		switch e := err.(type) {
+		case interface{ Unwrap() error }:
+			err = e.Unwrap()
+		case interface{ Cause() error }:
+			err = e.Cause()
+		default:
+			err = nil
+		}
+	}
+
+	return nil
+}
+
+func statusCode(err error) int {
+	if sc, ok := err.(interface{ StatusCode() int }); ok {
+		return sc.StatusCode()
+	}
+
+	v := reflect.Indirect(reflect.ValueOf(err))
+	if v.Kind() != reflect.Struct {
+		return 0
+	}
+
+	f := v.FieldByName("StatusCode")
+	if !f.IsValid() || f.Kind() != reflect.Int {
+		return 0
+	}
+
+	return int(f.Int())
+}
@@ -0,0 +1,117 @@
+package azure_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Sydsvenskan/copperhead"
+	"github.com/Sydsvenskan/copperhead/azure"
+)
+
+type responseError struct {
+	StatusCode int
+}
+
+func (e *responseError) Error() string { return "response error" }
+
+type fakeAppConfig map[string][]azure.Setting
+
+func (fc fakeAppConfig) ListSettings(
+	ctx context.Context, labelFilter string,
+) ([]azure.Setting, error) {
+	settings, ok := fc[labelFilter]
+	if !ok {
+		return nil, &responseError{StatusCode: 403}
+	}
+	return settings, nil
+}
+
+type fakeKeyVault map[string]string
+
+func (fk fakeKeyVault) ListSecretNames(ctx context.Context) ([]string, error) {
+	var names []string
+	for name := range fk {
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+func (fk fakeKeyVault) GetSecret(ctx context.Context, name string) (string, error) {
+	if name == "myapp-Database--User" {
+		return "", &responseError{StatusCode: 404}
+	}
+	return fk[name], nil
+}
+
+type appConf struct {
+	Name     string
+	Port     int
+	Database struct {
+		User     string
+		Password string
+	}
+}
+
+func str(s string) *string {
+	return &s
+}
+
+func TestAppConfig(t *testing.T) {
+	client := fakeAppConfig{
+		"prod": {
+			{Key: "name", Label: "prod", Value: str("app")},
+			{Key: "port", Label: "prod", Value: str("8080")},
+			{Key: "database:user", Label: "prod", Value: str("admin")},
+			{Key: "other/app", Label: "prod", Value: str("ignored")},
+		},
+	}
+
+	var conf appConf
+	err := copperhead.Configure(&conf,
+		azure.WithAppConfig(client, "prod"),
+	)
+	if err != nil {
+		t.Fatal("failed to load settings: " + err.Error())
+	}
+
+	if conf.Name != "app" || conf.Port != 8080 || conf.Database.User != "admin" {
+		t.Errorf("unexpected configuration %#v", conf)
+	}
+
+	err = copperhead.Configure(&conf,
+		azure.WithAppConfig(client, "staging"),
+	)
+	if !errors.Is(err, azure.ErrUnauthorized) {
+		t.Errorf("expected an unauthorized error, got: %v", err)
+	}
+}
+
+func TestKeyVault(t *testing.T) {
+	client := fakeKeyVault{
+		"myapp-Database--Password": "hunter2",
+		"myapp-port":               "8080",
+		"otherapp-Name":            "ignored",
+	}
+
+	var conf appConf
+	err := copperhead.Configure(&conf,
+		azure.WithKeyVault(client, "myapp-"),
+	)
+	if err != nil {
+		t.Fatal("failed to load secrets: " + err.Error())
+	}
+
+	if conf.Database.Password != "hunter2" || conf.Port != 8080 || conf.Name != "" {
+		t.Errorf("unexpected configuration %#v", conf)
+	}
+
+	client["myapp-Database--User"] = "admin"
+
+	err = copperhead.Configure(&conf,
+		azure.WithKeyVault(client, "myapp-"),
+	)
+	if !errors.Is(err, azure.ErrNotFound) {
+		t.Errorf("expected a not found error, got: %v", err)
+	}
+}
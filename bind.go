@@ -0,0 +1,97 @@
+package copperhead
+
+import (
+	"reflect"
+
+	"github.com/pkg/errors"
+)
+
+// BindStruct copies the fields of src, a struct or a pointer to a
+// struct of any type, to the fields of the configuration with the
+// same names. Nested structs are bound recursively, and nil pointers
+// to structs in src are skipped. Fields in src without a matching
+// configuration field are ignored, while fields with a type that
+// can't be assigned or converted to the type of the configuration
+// field are reported as errors.
+func (c *Config) BindStruct(src interface{}) error {
+	if err := c.requireStruct("binding structs"); err != nil {
+		return err
+	}
+
+	v := reflect.Indirect(reflect.ValueOf(src))
+	if !v.IsValid() || v.Kind() != reflect.Struct {
+		return errors.Errorf("src must be a struct, not a %T", src)
+	}
+
+	return c.track(Source{Type: SourceBind}, func() error {
+		return bindValue(c.obj, v, "")
+	})
+}
+
+func bindValue(dst, src reflect.Value, prefix string) error {
+	for i := 0; i < src.NumField(); i++ {
+		sf := src.Type().Field(i)
+		sv := src.Field(i)
+
+		df, ok := dst.Type().FieldByName(sf.Name)
+
+		// Fields of embedded structs are bound by their promoted
+		// names if the configuration has no field for the
+		// embedded struct itself.
+		if (!ok || sf.PkgPath != "") && sf.Anonymous && isMergeable(sv.Type()) {
+			if err := bindValue(dst, sv, prefix); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if !ok || sf.PkgPath != "" || df.PkgPath != "" {
+			continue
+		}
+
+		path := prefix + sf.Name
+
+		dv, err := dst.FieldByIndexErr(df.Index)
+		if err != nil {
+			return errors.Wrapf(err, "cannot bind %q", path)
+		}
+
+		if err := bindField(dv, sv, path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func bindField(dv, sv reflect.Value, path string) error {
+	st, dt := sv.Type(), dv.Type()
+
+	switch {
+	case st.AssignableTo(dt):
+		dv.Set(deepCopy(sv))
+		return nil
+	case st.ConvertibleTo(dt) && st.Kind() == dt.Kind():
+		dv.Set(deepCopy(sv).Convert(dt))
+		return nil
+	}
+
+	if st.Kind() == reflect.Ptr && isMergeable(st.Elem()) {
+		if sv.IsNil() {
+			return nil
+		}
+		sv, st = sv.Elem(), st.Elem()
+	}
+
+	if isMergeable(st) && isMergeable(baseType(dt)) {
+		z, err := ensureZero(path, dv)
+		if err != nil {
+			return err
+		}
+		return bindValue(*z, sv, path+".")
+	}
+
+	return errors.Errorf(
+		"cannot bind %q, a %q can't be assigned to a %q",
+		path, st.String(), dt.String())
+}
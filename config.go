@@ -3,25 +3,85 @@
 package copperhead
 
 import (
+	"bytes"
+	"context"
+	"database/sql"
 	"encoding"
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"net/url"
 	"os"
+	"path/filepath"
 	"reflect"
+	"sort"
+	"strconv"
 	"strings"
+	"text/template"
 
 	"github.com/pkg/errors"
 )
 
 // Config encapsulates configuration loading.
 type Config struct {
-	obj reflect.Value
+	ctx     context.Context
+	obj     reflect.Value
+	envVars map[string][]string
+
+	envSeparator rune
+	tagKey       string
+
+	numDelimiters string
+
+	missingEnv   func(field, env string) error
+	aliases      bool
+	logger       func(format string, args ...interface{})
+	units        bool
+	multiDoc     bool
+	flatKeys     bool
+	normalize    bool
+	ignoreSource bool
+	missingFile  func(name string)
+	envTransform func(field, raw string) string
+	derived      []func(c *Config) error
+	provenance   map[string][]Source
+	mapKeys      func(key string) string
+	deprecation  func(field, msg string)
+	validators   []func(c *Config) error
+	jsonDecode   func(data []byte, v interface{}) error
+
+	profile         string
+	profileFallback bool
+
+	migrations     map[int]func([]byte) ([]byte, error)
+	currentVersion int
+
+	optionalWhenEmpty bool
+
+	// target is the original configuration value while options
+	// are applied transactionally to obj.
+	target reflect.Value
+
+	// frozen is a snapshot of the configuration taken by Freeze.
+	frozen reflect.Value
+
+	preflightRequested bool
+	preflight          *preflightState
 }
 
 // Option configures our... inception!
 type Option func(c *Config) error
 
+// ContextAware creates an option that gets the context that was
+// passed to NewContext, or context.Background() when the
+// configuration was created using New. Options that do I/O should
+// use this to honor cancellation and deadlines.
+func ContextAware(fn func(ctx context.Context, c *Config) error) Option {
+	return func(c *Config) error {
+		return fn(c.Context(), c)
+	}
+}
+
 // WithEnvironment bootstraps our configuration with environment
 // variables.
 func WithEnvironment(envMap map[string]string) Option {
@@ -30,6 +90,14 @@ func WithEnvironment(envMap map[string]string) Option {
 	}
 }
 
+// WithEnvironmentFormat unmarshals an environment variable onto a
+// field, see Config.EnvironmentFormat.
+func WithEnvironmentFormat(field, env string, unm Unmarshaler) Option {
+	return func(c *Config) error {
+		return c.EnvironmentFormat(field, env, unm)
+	}
+}
+
 // Unmarshaler is something that can unmarshal a configuration.
 type Unmarshaler interface {
 	Unmarshal(data []byte, v interface{}) error
@@ -45,6 +113,12 @@ func (uf UnmarshalerFunc) Unmarshal(
 	return uf(data, v)
 }
 
+// ContextualUnmarshaler is an Unmarshaler that also receives the
+// name of the configuration source, usually the filename.
+type ContextualUnmarshaler interface {
+	UnmarshalContext(name string, data []byte, v interface{}) error
+}
+
 // WithConfigurationData reads the provided configuration data.
 func WithConfigurationData(data []byte, unm Unmarshaler) Option {
 	return func(c *Config) error {
@@ -52,15 +126,61 @@ func WithConfigurationData(data []byte, unm Unmarshaler) Option {
 	}
 }
 
+// WithConfigurationStdin reads configuration from standard input.
+func WithConfigurationStdin(mode FileMode, unm Unmarshaler) Option {
+	return func(c *Config) error {
+		return c.Stdin(mode, unm)
+	}
+}
+
 // FileMode controls file loading behaviour.
 type FileMode string
 
-// The file loading modes
+// The file loading modes. Missing files are errors when they're
+// required, are skipped when they're optional, and are skipped with
+// a warning with FileWarn, see WithMissingFileHandler.
 const (
 	FileRequired FileMode = "required"
 	FileOptional          = "optional"
+	FileWarn     FileMode = "warn"
 )
 
+// allowsMissing checks if the mode skips missing files.
+func (m FileMode) allowsMissing() bool {
+	return m == FileOptional || m == FileWarn
+}
+
+// WithMissingFileHandler sets a function that is called with the
+// name of every missing file, glob pattern, or directory that is
+// skipped because it's loaded with FileWarn. Without a handler the
+// warnings are logged, see WithLogger.
+func WithMissingFileHandler(fn func(name string)) Option {
+	return func(c *Config) error {
+		c.missingFile = fn
+		return nil
+	}
+}
+
+// skipMissing reports that a missing source is skipped because of
+// the mode.
+func (c *Config) skipMissing(mode FileMode, name, format string, args ...interface{}) {
+	if c.preflight != nil {
+		return
+	}
+
+	if mode != FileWarn {
+		c.logf(format, args...)
+		return
+	}
+
+	if c.missingFile != nil {
+		c.missingFile(name)
+		return
+	}
+
+	c.logf("warning: "+format, args...)
+}
+
 // WithConfigurationFile reads configuration from a file.
 func WithConfigurationFile(filename string, mode FileMode, unm Unmarshaler) Option {
 	return func(c *Config) error {
@@ -68,6 +188,54 @@ func WithConfigurationFile(filename string, mode FileMode, unm Unmarshaler) Opti
 	}
 }
 
+// WithConfigurationFileFromEnv reads configuration from a file
+// named by an environment variable, see Config.FileFromEnv.
+func WithConfigurationFileFromEnv(
+	env, defaultFilename string, mode FileMode, unm Unmarshaler,
+) Option {
+	return func(c *Config) error {
+		return c.FileFromEnv(env, defaultFilename, mode, unm)
+	}
+}
+
+// WithConfigurationFileTemplate reads configuration from a file with
+// a name that is a template expanded using the configuration loaded
+// by the preceding options, see Config.FileTemplate.
+func WithConfigurationFileTemplate(
+	nameTemplate string, mode FileMode, unm Unmarshaler,
+) Option {
+	return func(c *Config) error {
+		return c.FileTemplate(nameTemplate, mode, unm)
+	}
+}
+
+// WithConfigurationGlob reads configuration from all files matching
+// a glob pattern.
+func WithConfigurationGlob(pattern string, mode FileMode, unm Unmarshaler) Option {
+	return func(c *Config) error {
+		return c.Glob(pattern, mode, unm)
+	}
+}
+
+// WithConfigurationDir reads configuration from a directory where
+// every file holds the value of a field, as in mounted Kubernetes
+// ConfigMaps and Secrets.
+func WithConfigurationDir(dir string, mode FileMode) Option {
+	return func(c *Config) error {
+		return c.Dir(dir, mode)
+	}
+}
+
+// WithEncryptedFile reads configuration from an encrypted file.
+func WithEncryptedFile(
+	filename string, mode FileMode, unm Unmarshaler,
+	decrypt func([]byte) ([]byte, error),
+) Option {
+	return func(c *Config) error {
+		return c.EncryptedFile(filename, mode, unm, decrypt)
+	}
+}
+
 // Require verifies that configuration values are set.
 func Require(names ...string) Option {
 	return func(c *Config) error {
@@ -75,14 +243,110 @@ func Require(names ...string) Option {
 	}
 }
 
+// RequireDeep verifies that configuration values, and all the
+// fields of nested structs, are set.
+func RequireDeep(names ...string) Option {
+	return func(c *Config) error {
+		return c.RequireDeep(names...)
+	}
+}
+
+// MustExist verifies that configuration field names resolve.
+func MustExist(names ...string) Option {
+	return func(c *Config) error {
+		return c.MustExist(names...)
+	}
+}
+
+// RequireScheme verifies that a URL field is set and uses one of the
+// allowed schemes.
+func RequireScheme(name string, schemes ...string) Option {
+	return func(c *Config) error {
+		return c.RequireScheme(name, schemes...)
+	}
+}
+
+// RequireEach verifies that the named fields are set for every
+// element of a slice.
+func RequireEach(name string, fields ...string) Option {
+	return func(c *Config) error {
+		return c.RequireEach(name, fields...)
+	}
+}
+
+// RequireIf verifies that configuration values are set when a
+// condition field has a specific value.
+func RequireIf(condField, condValue string, names ...string) Option {
+	return func(c *Config) error {
+		return c.RequireIf(condField, condValue, names...)
+	}
+}
+
+// RequireOneOf verifies that exactly one of the named fields is
+// set.
+func RequireOneOf(names ...string) Option {
+	return func(c *Config) error {
+		return c.RequireOneOf(names...)
+	}
+}
+
+// RequireMutuallyExclusive verifies that at most one of the named
+// fields is set.
+func RequireMutuallyExclusive(names ...string) Option {
+	return func(c *Config) error {
+		return c.RequireMutuallyExclusive(names...)
+	}
+}
+
+// RequireAtLeast verifies that at least n of the named fields are
+// set.
+func RequireAtLeast(n int, names ...string) Option {
+	return func(c *Config) error {
+		return c.RequireAtLeast(n, names...)
+	}
+}
+
 // Configure populates conf.
 func Configure(conf interface{}, opts ...Option) error {
 	_, err := New(conf, opts...)
 	return err
 }
 
+// ConfigureContext populates conf, options are passed ctx through
+// Config.Context().
+func ConfigureContext(ctx context.Context, conf interface{}, opts ...Option) error {
+	_, err := NewContext(ctx, conf, opts...)
+	return err
+}
+
 // New creates a new configuration that populates conf.
 func New(conf interface{}, opts ...Option) (*Config, error) {
+	return NewContext(context.Background(), conf, opts...)
+}
+
+// Must is a helper function for creating the configuration at
+// program start. Panics if New returns an error.
+func Must(conf interface{}, opts ...Option) *Config {
+	c, err := New(conf, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// NewContext creates a new configuration that populates conf. The
+// context is available to options through Config.Context(), and
+// loading stops if the context is canceled.
+//
+// The conf is normally a pointer to a struct, but pointers to other
+// values, like maps, slices and scalars, can be populated from files
+// and data. Operations that need struct fields, like environment
+// mapping, fail for such configurations.
+func NewContext(ctx context.Context, conf interface{}, opts ...Option) (*Config, error) {
+	if ctx == nil {
+		return nil, errors.New("ctx cannot be nil")
+	}
+
 	if conf == nil {
 		return nil, errors.New("conf cannot be nil")
 	}
@@ -90,201 +354,1653 @@ func New(conf interface{}, opts ...Option) (*Config, error) {
 	v := reflect.ValueOf(conf)
 	if v.Type().Kind() != reflect.Ptr {
 		return nil, errors.New(
-			"conf must be a pointer to a struct")
+			"conf must be a pointer")
 	}
 
-	v = reflect.Indirect(reflect.ValueOf(conf))
-	if v.Kind() != reflect.Struct {
-		return nil, errors.New(
-			"conf must be a pointer to a struct")
+	if v.IsNil() {
+		return nil, errors.New("conf cannot be a nil pointer")
 	}
 
+	v = v.Elem()
+
 	c := &Config{
-		obj: v,
+		ctx:     ctx,
+		obj:     v,
+		envVars: make(map[string][]string),
+
+		envSeparator: ',',
+		tagKey:       DefaultTagKey,
+
+		numDelimiters: defaultNumDelimiters,
 	}
 
-	for _, opt := range opts {
+	for i, opt := range opts {
+		if err := ctx.Err(); err != nil {
+			return nil, errors.Wrap(err,
+				"configuration loading was interrupted")
+		}
+
 		if err := opt(c); err != nil {
 			return nil, err
 		}
+
+		if c.preflightRequested {
+			c.preflightRequested = false
+
+			if err := c.runPreflight(opts[i+1:]); err != nil {
+				return nil, err
+			}
+		}
 	}
 
-	return c, nil
-}
+	if err := c.finish(); err != nil {
+		return nil, err
+	}
 
-// Getenv reads a single environment variable.
-func (c *Config) Getenv(field, env string) error {
-	return c.Environment(map[string]string{
-		field: env,
-	})
-}
+	c.commit()
 
-// Environment populates our configuration with environment variables.
-func (c *Config) Environment(envMap map[string]string) error {
-	for name, envName := range envMap {
-		v, err := c.resolve(name)
-		if err != nil {
-			return errors.Wrapf(err,
-				"could not resolve %q", name)
-		}
+	return c, nil
+}
 
-		eVal, ok := os.LookupEnv(envName)
-		if !ok {
-			continue
+// finish normalizes the configuration and derives values after the
+// options have been applied.
+func (c *Config) finish() error {
+	if c.normalize {
+		if err := c.Normalize(); err != nil {
+			return err
 		}
+	}
 
-		if err := c.assign(v, eVal); err != nil {
-			return errors.Wrapf(err,
-				"could not assign the value of %q to %q",
-				envName, name,
-			)
+	for _, fn := range c.derived {
+		if err := fn(c); err != nil {
+			return errors.Wrap(err,
+				"failed to derive configuration values")
 		}
 	}
+
 	return nil
 }
 
-// File reads configuration from a file.
-func (c *Config) File(filename string, mode FileMode, unm Unmarshaler) error {
-	if unm == nil {
-		unm = UnmarshalerFunc(json.Unmarshal)
-	}
+// Context returns the context that the configuration was created
+// with.
+func (c *Config) Context() context.Context {
+	return c.ctx
+}
 
-	data, err := ioutil.ReadFile(filename)
-	if os.IsNotExist(err) && mode == FileOptional {
+// WithMissingEnvHandler sets a handler that is called when none of
+// the environment variables that a field is mapped to are set.
+// Fallback variable names are passed as a comma separated list.
+// Returning an error aborts the loading of environment variables,
+// returning nil leaves the field untouched, or sets it to the
+// default value if one has been specified.
+func WithMissingEnvHandler(handler func(field, env string) error) Option {
+	return func(c *Config) error {
+		c.missingEnv = handler
 		return nil
 	}
+}
 
-	if os.IsNotExist(err) {
-		return errors.Errorf(
-			"missing configuration file %q",
-			filename,
-		)
-	} else if err != nil {
-		return errors.Wrap(err,
-			"failed to read configuration file")
+// WithLogger sets a logging function that is used to trace how the
+// configuration is loaded. The values of secret fields are never
+// logged.
+func WithLogger(logger func(format string, args ...interface{})) Option {
+	return func(c *Config) error {
+		c.logger = logger
+		return nil
 	}
-
-	err = unm.Unmarshal(data, c.obj.Addr().Interface())
-	return errors.Wrapf(err,
-		"failed to unmarshal configuration file %q",
-		filename,
-	)
 }
 
-// Data reads the provided configuration data.
-func (c *Config) Data(data []byte, unm Unmarshaler) error {
-	if unm == nil {
-		unm = UnmarshalerFunc(json.Unmarshal)
+// WithStrictEnv fails if environment variables starting with prefix
+// haven't been consulted by any of the preceding options.
+func WithStrictEnv(prefix string) Option {
+	return func(c *Config) error {
+		return c.StrictEnv(prefix)
 	}
-	err := unm.Unmarshal(data, c.obj.Addr().Interface())
-	return errors.Wrap(err, "failed to unmarshal configuration data")
 }
 
-var urlType = reflect.TypeOf(url.URL{})
-
-func (c *Config) assign(target reflect.Value, val string) error {
-	v := reflect.ValueOf(val)
-
-	zero, err := ensureZero("target", target)
-	if err != nil {
-		return err
+// WithEnvValueTransform transforms environment variable values
+// before they are assigned, the transform gets the name of the
+// destination field so that it can be selective. Default values are
+// not transformed.
+func WithEnvValueTransform(transform func(field, raw string) string) Option {
+	return func(c *Config) error {
+		c.envTransform = transform
+		return nil
 	}
-	target = *zero
+}
 
-	if !target.CanSet() {
-		return errors.New("cannot set the value")
+// WithDerived registers a function that is called after all the
+// other options have been applied, so that it can derive values from
+// the loaded configuration. Derived functions are called in the order
+// they were registered.
+func WithDerived(fn func(c *Config) error) Option {
+	return func(c *Config) error {
+		c.derived = append(c.derived, fn)
+		return nil
 	}
+}
 
-	// Direct assignment
-	if v.Type().AssignableTo(target.Type()) {
-		target.Set(v)
+// WithEnvSeparator sets the separator that is used between fallback
+// variable names in environment mappings, defaults to a comma.
+func WithEnvSeparator(sep rune) Option {
+	return func(c *Config) error {
+		if sep == ':' || sep == '\\' {
+			return errors.Errorf(
+				"%q cannot be used as an env separator", sep)
+		}
+		c.envSeparator = sep
 		return nil
 	}
+}
 
-	iface := target.Addr().Interface()
-
-	// DEPRECATED: Special handling of URLs, because it's so
-	// common and why doesn't it implement TextUnmarshaler.
-	if target.Type().ConvertibleTo(urlType) {
-		ub := iface.(encoding.BinaryUnmarshaler)
-		err := ub.UnmarshalBinary([]byte(val))
-		if err != nil {
-			return err
+// WithNumberListDelimiters sets the characters that separate the
+// items of numeric lists, like []int and []float64, defaults to
+// commas and whitespace so that both "8080,8081" and "8080 8081"
+// are accepted.
+func WithNumberListDelimiters(delimiters string) Option {
+	return func(c *Config) error {
+		if delimiters == "" {
+			return errors.New("the list delimiters cannot be empty")
 		}
+		c.numDelimiters = delimiters
 		return nil
 	}
+}
 
-	// Generic text unmarshaling
-	if tx, ok := iface.(encoding.TextUnmarshaler); ok {
-		err := tx.UnmarshalText([]byte(val))
-		if err != nil {
-			return err
+// WithJSONDecoder replaces json.Unmarshal as the decoder that is
+// used for values that are assigned as JSON, and as the default
+// unmarshaler for files and data. This makes it possible to
+// f.ex. use a json.Decoder with UseNumber() or a faster JSON
+// library.
+func WithJSONDecoder(decode func(data []byte, v interface{}) error) Option {
+	return func(c *Config) error {
+		if decode == nil {
+			return errors.New("the JSON decoder cannot be nil")
 		}
+		c.jsonDecode = decode
 		return nil
 	}
+}
 
-	// Fall back to JSON unmarshalling
-	err = json.Unmarshal([]byte(val), iface)
-	return errors.Wrap(err, "failed to decode value as JSON")
+// jsonUnmarshaler returns the configured JSON decoder, or
+// json.Unmarshal.
+func (c *Config) jsonUnmarshaler() Unmarshaler {
+	if c.jsonDecode != nil {
+		return UnmarshalerFunc(c.jsonDecode)
+	}
+	return UnmarshalerFunc(json.Unmarshal)
 }
 
-// Require checks if congiguration values are set.
-func (c *Config) Require(names ...string) error {
-	for _, name := range names {
-		v, err := c.resolve(name)
-		if err != nil {
-			return errors.Wrapf(err,
-				"failed to resolve %q", name)
-		}
+// WithEnvironmentJSON reads configuration data from an environment
+// variable, see Config.EnvironmentJSON.
+func WithEnvironmentJSON(env string, unm Unmarshaler) Option {
+	return func(c *Config) error {
+		return c.EnvironmentJSON(env, unm)
+	}
+}
 
-		if v.Kind() == reflect.Ptr {
-			if v.IsNil() {
-				return errors.Errorf("%q is nil", name)
-			}
-			continue
-		}
+// EnvironmentJSON unmarshals the value of an environment variable
+// onto the whole configuration, nothing is done if the variable is
+// unset. The variables of all calls are included in EnvMapping() with
+// an empty field name.
+func (c *Config) EnvironmentJSON(env string, unm Unmarshaler) error {
+	known := false
+	for _, name := range c.envVars[""] {
+		known = known || name == env
+	}
 
-		if v.Kind() == reflect.Bool {
-			// Required isn't a meaningful concept for
-			// booleans.
-			continue
-		}
+	if !known {
+		c.envVars[""] = append(c.envVars[""], env)
+	}
 
-		zero := reflect.New(v.Type()).Elem()
+	value, ok := os.LookupEnv(env)
+	if !ok {
+		c.logf("skipping configuration data, %s is unset", env)
+		return nil
+	}
 
-		if zero.Interface() == v.Interface() {
-			return errors.Errorf(
-				"%q is empty", name)
-		}
+	c.logf("read configuration data from %q", env)
 
+	if unm == nil {
+		unm = c.jsonUnmarshaler()
 	}
-	return nil
+
+	err := c.unmarshalNamed(Source{Type: SourceEnv, Name: env},
+		env, []byte(value), unm)
+	return errors.Wrapf(err,
+		"failed to unmarshal configuration from the environment variable %q",
+		env)
 }
 
-func (c *Config) resolve(name string) (reflect.Value, error) {
-	path := strings.Split(name, ".")
+// EnvironmentFormat unmarshals the value of an environment variable
+// onto a single field, so that a struct field can be given as f.ex.
+// a YAML snippet. The decoded value is merged into the field in the
+// same way as when loading a file, and nothing is done if the
+// variable is unset.
+func (c *Config) EnvironmentFormat(field, env string, unm Unmarshaler) error {
+	if err := c.checkFrozen(); err != nil {
+		return err
+	}
 
-	n := c.obj
-	for len(path) > 0 {
-		head := path[0]
-		path = path[1:]
+	v, err := c.resolve(field)
+	if err != nil {
+		return errors.Wrapf(err,
+			"could not resolve %q", field)
+	}
 
-		if n.Kind() != reflect.Struct {
-			return n, errors.Errorf(
-				"cannot get field %q from a %q value",
-				head, n.Kind().String(),
-			)
-		}
+	c.envVars[field] = []string{env}
 
-		field := n.FieldByName(head)
-		if !field.IsValid() {
-			return n, errors.Errorf(
-				"%q doesn't have a field %q",
-				n.Type().Name(), head,
-			)
-		}
+	value, ok := os.LookupEnv(env)
+	if !ok {
+		c.logf("skipping %s, %s is unset", field, env)
+		return nil
+	}
 
-		if len(path) > 0 {
+	c.logf("assigning %s from %q: %s",
+		field, env, c.logValue(field, value))
+
+	if unm == nil {
+		unm = c.jsonUnmarshaler()
+	}
+
+	err = c.track(Source{Type: SourceEnv, Name: env}, func() error {
+		err := unmarshalInto(env, []byte(value), unm, v.Addr().Interface())
+		if err != nil {
+			return err
+		}
+		return c.normalizeMapKeys(field, v)
+	})
+	return errors.Wrapf(err,
+		"could not assign the value of %q to %q", env, field)
+}
+
+// Getenv reads a single environment variable.
+func (c *Config) Getenv(field, env string) error {
+	return c.Environment(map[string]string{
+		field: env,
+	})
+}
+
+// GetenvAll reads environment variables in the same way as
+// Environment, but doesn't stop at the first failure. The returned
+// map has an entry for every field in envMap, with a nil error for
+// the fields that were read successfully or whose variables are
+// unset.
+func (c *Config) GetenvAll(envMap map[string]string) map[string]error {
+	errs := make(map[string]error, len(envMap))
+
+	for field, spec := range envMap {
+		errs[field] = c.Environment(map[string]string{
+			field: spec,
+		})
+	}
+
+	return errs
+}
+
+// Set assigns a value to a single field using the same conversion
+// rules as for environment variables.
+func (c *Config) Set(name, value string) error {
+	return c.set(name, value, Source{Type: SourceSet})
+}
+
+func (c *Config) set(name, value string, src Source) error {
+	if err := c.checkFrozen(); err != nil {
+		return err
+	}
+
+	if ok, err := c.CheckSource(name, src); !ok {
+		return err
+	}
+
+	assign, err := c.assigner(name)
+	if err != nil {
+		return errors.Wrapf(err,
+			"could not resolve %q", name)
+	}
+
+	c.logf("assigning %s: %s", name, c.logValue(name, value))
+
+	err = assign(value)
+	if err != nil {
+		return errors.Wrapf(err,
+			"could not assign value to %q", name)
+	}
+
+	c.record(name, src)
+
+	return nil
+}
+
+// Environment populates our configuration with environment variables.
+//
+// The variable names can include a default value that is used when
+// the variable is unset, as in "APP_PORT:8080". Everything after the
+// first unescaped colon is the default value. Several variable names
+// can be given as a comma separated list, as in
+// "SERVICE_URL,APP_URL", and the first one that is set will be used.
+// The separator can be changed using WithEnvSeparator. Colons,
+// separators, and backslashes are escaped with a backslash, so
+// `APP\:ADDR:C:\\tmp` reads the variable "APP:ADDR" with the default
+// `C:\tmp`.
+//
+// Variable names can reference other variables, so
+// "APP_${REGION}_URL" reads "APP_eu_URL" when REGION is "eu".
+func (c *Config) Environment(envMap map[string]string) error {
+	if err := c.checkFrozen(); err != nil {
+		return err
+	}
+
+	for name, spec := range envMap {
+		assign, err := c.assigner(name)
+		if err != nil {
+			return errors.Wrapf(err,
+				"could not resolve %q", name)
+		}
+
+		envNames, def, hasDefault := parseEnvSpec(spec, c.envSeparator)
+
+		envNames, err = expandEnvNames(envNames)
+		if err != nil {
+			return errors.Wrapf(err,
+				"invalid environment mapping for %q", name)
+		}
+
+		c.envVars[name] = envNames
+
+		envName, eVal, ok := lookupEnvNames(envNames)
+		if ok && c.envTransform != nil {
+			eVal = c.envTransform(name, eVal)
+		}
+
+		if ok {
+			c.logf("assigning %s from %q: %s",
+				name, envName, c.logValue(name, eVal))
+		} else if hasDefault {
+			c.logf("assigning %s from default: %s",
+				name, c.logValue(name, def))
+		} else {
+			c.logf("skipping %s, %s is unset",
+				name, strings.Join(envNames, ", "))
+		}
+
+		if !ok && c.missingEnv != nil && c.preflight == nil {
+			err := c.missingEnv(name, strings.Join(envNames, ","))
+			if err != nil {
+				return err
+			}
+		}
+
+		if !ok && hasDefault {
+			eVal = def
+		} else if !ok {
+			continue
+		}
+
+		src := Source{Type: SourceDefault}
+		if ok {
+			src = Source{Type: SourceEnv, Name: envName}
+		}
+
+		if allowed, err := c.CheckSource(name, src); !allowed {
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := assign(eVal); err != nil {
+			return errors.Wrapf(err,
+				"could not assign the value of %q to %q",
+				envName, name,
+			)
+		}
+
+		c.record(name, src)
+	}
+	return nil
+}
+
+// lookupEnvNames returns the value of the first environment
+// variable that is set.
+func lookupEnvNames(names []string) (name, value string, ok bool) {
+	for _, name := range names {
+		if value, ok := os.LookupEnv(name); ok {
+			return name, value, true
+		}
+	}
+	return "", "", false
+}
+
+// EnvVars returns the sorted names of all the environment variables
+// that the configuration has consulted.
+func (c *Config) EnvVars() []string {
+	seen := make(map[string]bool, len(c.envVars))
+	names := make([]string, 0, len(c.envVars))
+
+	for _, envNames := range c.envVars {
+		for _, envName := range envNames {
+			if seen[envName] {
+				continue
+			}
+			seen[envName] = true
+			names = append(names, envName)
+		}
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// EnvMapping returns the environment variable names that the
+// configuration has consulted, keyed by field name. When a field has
+// been mapped several times the last mapping is used, fallback
+// variable names are separated by commas.
+func (c *Config) EnvMapping() map[string]string {
+	m := make(map[string]string, len(c.envVars))
+	for name, envNames := range c.envVars {
+		m[name] = strings.Join(envNames, ",")
+	}
+	return m
+}
+
+// StrictEnv checks that every environment variable that starts
+// with prefix has been consulted by the configuration. Unexpected
+// variables, often typos, are listed in the returned error.
+func (c *Config) StrictEnv(prefix string) error {
+	if prefix == "" {
+		return errors.New("the prefix cannot be empty")
+	}
+
+	known := make(map[string]bool)
+	for _, name := range c.EnvVars() {
+		known[name] = true
+	}
+
+	var unexpected []string
+
+	for _, kv := range os.Environ() {
+		name := strings.SplitN(kv, "=", 2)[0]
+		if strings.HasPrefix(name, prefix) && !known[name] {
+			unexpected = append(unexpected, name)
+		}
+	}
+
+	if len(unexpected) > 0 {
+		sort.Strings(unexpected)
+		return errors.Errorf(
+			"unexpected environment variables: %s",
+			quoteList(unexpected),
+		)
+	}
+
+	return nil
+}
+
+// expandEnvNames expands references to environment variables, as in
+// "APP_${REGION}_URL", in environment variable names. Referencing an
+// unset variable is an error.
+func expandEnvNames(names []string) ([]string, error) {
+	expanded := make([]string, len(names))
+
+	for i, name := range names {
+		var missing []string
+
+		expanded[i] = os.Expand(name, func(v string) string {
+			value, ok := os.LookupEnv(v)
+			if !ok {
+				missing = append(missing, v)
+			}
+			return value
+		})
+
+		if len(missing) > 0 {
+			return nil, errors.Errorf(
+				"the environment variable name %q references unset variables: %s",
+				name, quoteList(missing))
+		}
+	}
+
+	return expanded, nil
+}
+
+// parseEnvSpec splits an environment spec into the variable names
+// and an optional default value.
+func parseEnvSpec(spec string, sep rune) (names []string, def string, hasDefault bool) {
+	var (
+		buf     strings.Builder
+		escaped bool
+	)
+
+	for _, r := range spec {
+		switch {
+		case escaped:
+			buf.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == sep && !hasDefault:
+			names = append(names, buf.String())
+			buf.Reset()
+		case r == ':' && !hasDefault:
+			names = append(names, buf.String())
+			buf.Reset()
+			hasDefault = true
+		default:
+			buf.WriteRune(r)
+		}
+	}
+
+	if escaped {
+		buf.WriteRune('\\')
+	}
+
+	if !hasDefault {
+		return append(names, buf.String()), "", false
+	}
+
+	return names, buf.String(), true
+}
+
+// File reads configuration from a file.
+func (c *Config) File(filename string, mode FileMode, unm Unmarshaler) error {
+	if unm == nil {
+		unm = c.jsonUnmarshaler()
+	}
+
+	data, ok, err := c.readConfigFile(filename, mode)
+	if err != nil || !ok {
+		return err
+	}
+
+	err = c.unmarshalNamed(Source{Type: SourceFile, Name: filename},
+		filename, data, unm)
+	return errors.Wrapf(err,
+		"failed to unmarshal configuration file %q",
+		filename,
+	)
+}
+
+// FileFromEnv reads configuration from the file named by the
+// environment variable env, or from defaultFilename if the variable
+// is unset or empty. The mode applies to whichever file is used.
+func (c *Config) FileFromEnv(env, defaultFilename string, mode FileMode, unm Unmarshaler) error {
+	filename := os.Getenv(env)
+	if filename == "" {
+		c.logf("%s is unset, using the configuration file %q",
+			env, defaultFilename)
+		filename = defaultFilename
+	}
+
+	return c.File(filename, mode, unm)
+}
+
+// FileTemplate reads configuration from a file with a name that is
+// a text/template expanded using the current configuration, so
+// "config.{{.Env}}.yaml" uses the value of the Env field. Load the
+// values that the template depends on before calling FileTemplate.
+func (c *Config) FileTemplate(nameTemplate string, mode FileMode, unm Unmarshaler) error {
+	tmpl, err := template.New("filename").
+		Option("missingkey=error").Parse(nameTemplate)
+	if err != nil {
+		return errors.Wrapf(err,
+			"invalid filename template %q", nameTemplate)
+	}
+
+	var name strings.Builder
+	if err := tmpl.Execute(&name, c.obj.Interface()); err != nil {
+		return errors.Wrapf(err,
+			"failed to expand filename template %q", nameTemplate)
+	}
+
+	return c.File(name.String(), mode, unm)
+}
+
+// Glob reads configuration from all files matching the pattern, in
+// lexicographical order, so that values from later files override
+// earlier ones. It's an error if nothing matches and mode is
+// FileRequired.
+func (c *Config) Glob(pattern string, mode FileMode, unm Unmarshaler) error {
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return errors.Wrapf(err, "invalid glob pattern %q", pattern)
+	}
+
+	if c.preflight != nil && !c.preflight.checkGlob(pattern, mode) {
+		return nil
+	}
+
+	if len(matches) == 0 && mode.allowsMissing() {
+		c.skipMissing(mode, pattern,
+			"no files matching optional pattern %q", pattern)
+		return nil
+	}
+
+	if len(matches) == 0 {
+		return errors.Errorf(
+			"no configuration files matching %q", pattern)
+	}
+
+	sort.Strings(matches)
+
+	for _, filename := range matches {
+		if err := c.File(filename, FileRequired, unm); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Dir reads configuration from a directory where the name of each
+// file is the field name, with dots for nesting, and the trimmed
+// contents of the file is the value. Hidden files and directories are
+// ignored, which skips the bookkeeping entries of Kubernetes volumes.
+func (c *Config) Dir(dir string, mode FileMode) error {
+	if c.preflight != nil && !c.preflight.checkDir(dir, mode) {
+		return nil
+	}
+
+	entries, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) && mode.allowsMissing() {
+		c.skipMissing(mode, dir,
+			"skipping missing optional directory %q", dir)
+		return nil
+	} else if os.IsNotExist(err) {
+		return errors.Errorf("missing configuration directory %q", dir)
+	} else if err != nil {
+		return errors.Wrap(err, "failed to read configuration directory")
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+
+		filename := filepath.Join(dir, name)
+
+		// Stat the file to follow symlinks.
+		info, err := os.Stat(filename)
+		if err != nil {
+			return errors.Wrap(err, "failed to read configuration file")
+		}
+
+		if info.IsDir() {
+			continue
+		}
+
+		data, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return errors.Wrap(err, "failed to read configuration file")
+		}
+
+		err = c.set(name, strings.TrimSpace(string(data)),
+			Source{Type: SourceFile, Name: filename})
+		if err != nil {
+			return errors.Wrapf(err,
+				"failed to assign configuration file %q", filename)
+		}
+	}
+
+	return nil
+}
+
+// EncryptedFile reads configuration from a file that is decrypted
+// using the provided decrypt function before it's unmarshaled.
+func (c *Config) EncryptedFile(
+	filename string, mode FileMode, unm Unmarshaler,
+	decrypt func([]byte) ([]byte, error),
+) error {
+	if decrypt == nil {
+		return errors.New("decrypt cannot be nil")
+	}
+
+	if unm == nil {
+		unm = c.jsonUnmarshaler()
+	}
+
+	data, ok, err := c.readConfigFile(filename, mode)
+	if err != nil || !ok {
+		return err
+	}
+
+	plain, err := decrypt(data)
+	if err != nil {
+		return errors.Wrapf(err,
+			"failed to decrypt configuration file %q",
+			filename,
+		)
+	}
+
+	err = c.unmarshalNamed(Source{Type: SourceFile, Name: filename},
+		filename, plain, unm)
+	return errors.Wrapf(err,
+		"failed to unmarshal configuration file %q",
+		filename,
+	)
+}
+
+// readConfigFile reads a configuration file, ok will be false if
+// the file is missing and optional.
+func (c *Config) readConfigFile(filename string, mode FileMode) (data []byte, ok bool, err error) {
+	// Files that pass the preflight check are read, so that the
+	// options that follow see their values.
+	if c.preflight != nil && !c.preflight.checkFile(filename, mode) {
+		return nil, false, nil
+	}
+
+	data, err = ioutil.ReadFile(filename)
+	if os.IsNotExist(err) && mode.allowsMissing() {
+		c.skipMissing(mode, filename,
+			"skipping missing optional file %q", filename)
+		return nil, false, nil
+	}
+
+	if os.IsNotExist(err) {
+		return nil, false, errors.Errorf(
+			"missing configuration file %q",
+			filename,
+		)
+	} else if err != nil {
+		return nil, false, errors.Wrap(err,
+			"failed to read configuration file")
+	}
+
+	c.logf("read configuration file %q", filename)
+
+	return data, true, nil
+}
+
+// Data reads the provided configuration data.
+func (c *Config) Data(data []byte, unm Unmarshaler) error {
+	if unm == nil {
+		unm = c.jsonUnmarshaler()
+	}
+	err := c.unmarshalNamed(Source{Type: SourceData}, "", data, unm)
+	return errors.Wrap(err, "failed to unmarshal configuration data")
+}
+
+// Stdin reads all of standard input and unmarshals it as
+// configuration data. Empty input is an error when mode is
+// FileRequired and is skipped when mode is FileOptional.
+func (c *Config) Stdin(mode FileMode, unm Unmarshaler) error {
+	if c.preflight != nil {
+		return nil
+	}
+
+	data, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		return errors.Wrap(err, "failed to read configuration from stdin")
+	}
+
+	if len(bytes.TrimSpace(data)) == 0 {
+		if mode.allowsMissing() {
+			c.skipMissing(mode, "-", "skipping empty optional stdin")
+			return nil
+		}
+		return errors.New("missing configuration on stdin")
+	}
+
+	c.logf("read configuration from stdin")
+
+	if unm == nil {
+		unm = c.jsonUnmarshaler()
+	}
+	err = c.unmarshalNamed(Source{Type: SourceStdin}, "-", data, unm)
+	return errors.Wrap(err, "failed to unmarshal configuration from stdin")
+}
+
+// Merge copies all non-zero fields from src onto the
+// configuration. The src must be a value of, or a pointer to, the
+// same struct type as the configuration. Nested structs are merged
+// recursively.
+func (c *Config) Merge(src interface{}) error {
+	if src == nil {
+		return errors.New("src cannot be nil")
+	}
+
+	v := reflect.Indirect(reflect.ValueOf(src))
+	if !v.IsValid() {
+		return errors.New("src cannot be a nil pointer")
+	}
+
+	if v.Type() != c.obj.Type() {
+		return errors.Errorf(
+			"cannot merge a %q into a %q",
+			v.Type().String(), c.obj.Type().String(),
+		)
+	}
+
+	if err := c.checkFrozen(); err != nil {
+		return err
+	}
+
+	if v.Kind() != reflect.Struct {
+		if !v.IsZero() {
+			c.obj.Set(v)
+		}
+		return nil
+	}
+
+	return c.track(Source{Type: SourceMerge}, func() error {
+		return mergeValue(c.obj, v)
+	})
+}
+
+// requireStruct returns an error if the configuration isn't a
+// struct.
+func (c *Config) requireStruct(operation string) error {
+	if c.obj.Kind() != reflect.Struct {
+		return errors.Errorf(
+			"%s requires a struct configuration, not a %q",
+			operation, c.obj.Type().String(),
+		)
+	}
+	return nil
+}
+
+func mergeValue(dst, src reflect.Value) error {
+	for i := 0; i < src.NumField(); i++ {
+		sf := src.Type().Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+
+		sv := src.Field(i)
+		if sv.IsZero() {
+			continue
+		}
+
+		df := dst.Field(i)
+
+		if isMergeable(sv.Type()) {
+			if err := mergeValue(df, sv); err != nil {
+				return errors.Wrapf(err,
+					"failed to merge %q", sf.Name)
+			}
+			continue
+		}
+
+		if sv.Kind() == reflect.Ptr && isMergeable(sv.Type().Elem()) {
+			z, err := ensureZero(sf.Name, df)
+			if err != nil {
+				return err
+			}
+
+			if err := mergeValue(*z, sv.Elem()); err != nil {
+				return errors.Wrapf(err,
+					"failed to merge %q", sf.Name)
+			}
+			continue
+		}
+
+		df.Set(sv)
+	}
+
+	return nil
+}
+
+var (
+	textUnmarshalerType = reflect.TypeOf(
+		(*encoding.TextUnmarshaler)(nil)).Elem()
+	binaryUnmarshalerType = reflect.TypeOf(
+		(*encoding.BinaryUnmarshaler)(nil)).Elem()
+)
+
+// isMergeable checks if a type is a struct that we can merge field
+// by field. Structs with unexported fields, and structs that
+// implement TextUnmarshaler, are treated as atomic values.
+func isMergeable(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+
+	if reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		return false
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).PkgPath != "" {
+			return false
+		}
+	}
+
+	return true
+}
+
+// unmarshalNamed unmarshals data from a named source, using
+// UnmarshalContext if unm is a ContextualUnmarshaler and we have a
+// name. The fields that the data assigns are recorded as set by src.
+func (c *Config) unmarshalNamed(src Source, name string, data []byte, unm Unmarshaler) error {
+	if !c.multiDoc {
+		return c.unmarshalDocument(src, name, data, unm)
+	}
+
+	docs, err := splitDocuments(data)
+	if err != nil {
+		return err
+	}
+
+	for i, doc := range docs {
+		if isEmptyDocument(doc) {
+			continue
+		}
+
+		if err := c.unmarshalDocument(src, name, doc, unm); err != nil {
+			return errors.Wrapf(err,
+				"failed to unmarshal document at index %d", i)
+		}
+	}
+
+	return nil
+}
+
+func (c *Config) unmarshalDocument(src Source, name string, data []byte, unm Unmarshaler) error {
+	if c.migrations != nil {
+		migrated, err := c.migrate(name, data, unm)
+		if err != nil {
+			return err
+		}
+		data = migrated
+	}
+
+	decode := func() error {
+		return c.decodeDocument(name, data, unm)
+	}
+
+	// Profiles and flattened keys aren't decoded into the whole
+	// configuration, so they are tracked by the fields they change.
+	if c.profile != "" || c.flatKeys {
+		return c.track(src, decode)
+	}
+
+	paths, ok := c.assignedPaths(name, data, unm)
+	if !ok {
+		return c.track(src, decode)
+	}
+
+	return c.trackAssigned(src, paths, decode)
+}
+
+func (c *Config) decodeDocument(name string, data []byte, unm Unmarshaler) error {
+	if c.profile != "" {
+		return c.unmarshalProfiles(name, data, unm)
+	}
+
+	if c.flatKeys {
+		if err := c.unmarshalFlat(name, data, unm); err != nil {
+			return err
+		}
+		return c.normalizeMapKeys("", c.obj)
+	}
+
+	// Aliases are applied first so that the regular keys take
+	// precedence.
+	if c.aliases {
+		if err := c.unmarshalAliases(name, data, unm); err != nil {
+			return err
+		}
+	}
+
+	err := unmarshalInto(name, data, unm, c.obj.Addr().Interface())
+	if err != nil {
+		return err
+	}
+
+	return c.normalizeMapKeys("", c.obj)
+}
+
+func unmarshalInto(name string, data []byte, unm Unmarshaler, v interface{}) error {
+	if cu, ok := unm.(ContextualUnmarshaler); ok && name != "" {
+		return cu.UnmarshalContext(name, data, v)
+	}
+	return unm.Unmarshal(data, v)
+}
+
+var urlType = reflect.TypeOf(url.URL{})
+
+func (c *Config) assign(target reflect.Value, val string) error {
+	v := reflect.ValueOf(val)
+
+	zero, err := ensureZero("target", target)
+	if err != nil {
+		return err
+	}
+	target = *zero
+
+	if !target.CanSet() {
+		return errors.New("cannot set the value")
+	}
+
+	// Direct assignment
+	if v.Type().AssignableTo(target.Type()) {
+		target.Set(v)
+		return nil
+	}
+
+	// Registered interface implementations
+	if target.Kind() == reflect.Interface {
+		ok, err := assignImpl(target, val)
+		if ok {
+			return err
+		}
+	}
+
+	iface := target.Addr().Interface()
+
+	// DEPRECATED: Special handling of URLs, because it's so
+	// common and why doesn't it implement TextUnmarshaler.
+	if target.Type().ConvertibleTo(urlType) {
+		ub := iface.(encoding.BinaryUnmarshaler)
+		err := ub.UnmarshalBinary([]byte(val))
+		if err != nil {
+			return err
+		}
+		return nil
+	}
+
+	// JSON numbers keep their exact representation
+	if target.Type() == jsonNumberType {
+		return assignJSONNumber(target, val)
+	}
+
+	// Arbitrary precision numbers
+	if ok, err := assignBig(target, val); ok {
+		return err
+	}
+
+	// Generic text unmarshaling
+	if tx, ok := iface.(encoding.TextUnmarshaler); ok {
+		err := tx.UnmarshalText([]byte(val))
+		if err != nil {
+			return err
+		}
+		return nil
+	}
+
+	// Database types that only implement sql.Scanner
+	if sc, ok := iface.(sql.Scanner); ok {
+		err := sc.Scan(val)
+		return errors.Wrap(err, "failed to scan value")
+	}
+
+	// Numbers are parsed explicitly to get consistent handling of
+	// signs, exponents, and ranges.
+	if _, ok := iface.(json.Unmarshaler); !ok && isNumber(target.Kind()) {
+		return assignNumber(target, val)
+	}
+
+	// Comma separated lists, unless the value is a JSON array
+	if isList(target.Type()) && !strings.HasPrefix(strings.TrimSpace(val), "[") {
+		return c.assignList(target, val)
+	}
+
+	// Fall back to JSON unmarshalling
+	err = c.jsonUnmarshaler().Unmarshal([]byte(val), iface)
+	return errors.Wrap(err, "failed to decode value as JSON")
+}
+
+// isList checks if t is a slice that we can populate from a comma
+// separated list. Byte slices are excluded.
+func isList(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8
+}
+
+// defaultNumDelimiters separate the items of numeric lists.
+const defaultNumDelimiters = ", \t\r\n"
+
+// assignList splits val on commas and assigns each item to an
+// element of the slice target. Numeric lists are split on any of the
+// number delimiters, and empty items are skipped.
+func (c *Config) assignList(target reflect.Value, val string) error {
+	var items []string
+
+	switch {
+	case isNumber(baseType(target.Type().Elem()).Kind()):
+		items = strings.FieldsFunc(val, func(r rune) bool {
+			return strings.ContainsRune(c.numDelimiters, r)
+		})
+	case strings.TrimSpace(val) != "":
+		items = strings.Split(val, ",")
+	}
+
+	list := reflect.MakeSlice(target.Type(), len(items), len(items))
+
+	for i, item := range items {
+		item = strings.TrimSpace(item)
+
+		err := c.assign(list.Index(i), item)
+		if err != nil {
+			return errors.Wrapf(err,
+				"failed to assign list item %d (%q)", i, item)
+		}
+	}
+
+	target.Set(list)
+
+	return nil
+}
+
+// Require checks if congiguration values are set.
+func (c *Config) Require(names ...string) error {
+	if c.skipRequired() {
+		return nil
+	}
+
+	for _, name := range names {
+		v, err := c.resolve(name)
+		if err != nil {
+			return errors.Wrapf(err,
+				"failed to resolve %q", name)
+		}
+
+		if err := checkRequired(name, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Setter can be implemented by types that track whether they have
+// been explicitly assigned, so that Require can tell a value that
+// was set to the zero value apart from an unset value.
+type Setter interface {
+	IsSet() bool
+}
+
+var setterType = reflect.TypeOf((*Setter)(nil)).Elem()
+
+// checkSetter checks if v, or a pointer to v, implements Setter.
+func checkSetter(v reflect.Value) (set bool, ok bool) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return false, false
+		}
+	}
+
+	switch {
+	case v.Type().Implements(setterType):
+		return v.Interface().(Setter).IsSet(), true
+	case v.CanAddr() && reflect.PtrTo(v.Type()).Implements(setterType):
+		return v.Addr().Interface().(Setter).IsSet(), true
+	}
+
+	return false, false
+}
+
+// checkRequired checks that a required value is set. Values that
+// implement Setter are set if IsSet returns true.
+func checkRequired(name string, v reflect.Value) error {
+	if !v.CanInterface() {
+		return checkZero(name, v)
+	}
+
+	if set, ok := checkSetter(v); ok {
+		if !set {
+			return errors.Errorf("%q is unset", name)
+		}
+		return nil
+	}
+
+	if v.Kind() == reflect.Ptr && !v.IsNil() {
+		if set, ok := checkSetter(v.Elem()); ok && !set {
+			return errors.Errorf("%q is unset", name)
+		}
+	}
+
+	return checkZero(name, v)
+}
+
+// checkZero checks that a value isn't empty.
+func checkZero(name string, v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Func:
+		if v.IsNil() {
+			return errors.Errorf("%q is nil", name)
+		}
+		return nil
+	case reflect.Slice, reflect.Map:
+		if v.Len() == 0 {
+			return errors.Errorf("%q is empty", name)
+		}
+		return nil
+	case reflect.Bool:
+		// Required isn't a meaningful concept for
+		// booleans.
+		return nil
+	}
+
+	// Compare using reflection as the value might be of an
+	// uncomparable type, like a struct containing a slice.
+	if v.IsZero() {
+		return errors.Errorf(
+			"%q is empty", name)
+	}
+
+	return nil
+}
+
+// RequireEach checks that a slice or array is non-empty and that
+// the named fields are set for each of its elements.
+func (c *Config) RequireEach(name string, fields ...string) error {
+	if c.skipRequired() {
+		return nil
+	}
+
+	v, err := c.resolve(name)
+	if err != nil {
+		return errors.Wrapf(err,
+			"failed to resolve %q", name)
+	}
+
+	v = reflect.Indirect(v)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return errors.Errorf(
+			"%q is a %q, not a slice or array",
+			name, v.Kind().String(),
+		)
+	}
+
+	if v.Len() == 0 {
+		return errors.Errorf("%q is empty", name)
+	}
+
+	for i := 0; i < v.Len(); i++ {
+		elemName := fmt.Sprintf("%s[%d]", name, i)
+
+		elem := v.Index(i)
+		for elem.Kind() == reflect.Ptr {
+			if elem.IsNil() {
+				return errors.Errorf("%q is nil", elemName)
+			}
+			elem = elem.Elem()
+		}
+
+		for _, field := range fields {
+			fieldName := elemName + "." + field
+
+			fv, err := walk(elem, field, false, c.aliasTag())
+			if err != nil {
+				return errors.Wrapf(err,
+					"failed to resolve %q", fieldName)
+			}
+
+			if err := checkRequired(fieldName, fv); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// RequireIf checks that configuration values are set, see Require,
+// if the string form of condField equals condValue. Values that
+// implement fmt.Stringer are compared using String(), and a
+// condField behind a nil pointer never matches.
+func (c *Config) RequireIf(condField, condValue string, names ...string) error {
+	if c.skipRequired() {
+		return nil
+	}
+
+	if _, ok := fieldByPath(c.obj.Type(), condField, c.aliasTag()); !ok {
+		return errors.Errorf(
+			"unknown configuration field %q", condField)
+	}
+
+	v, err := c.lookup(condField)
+	if err != nil {
+		return nil
+	}
+
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	if !v.CanInterface() {
+		return nil
+	}
+
+	value := v.Interface()
+	if v.CanAddr() {
+		if s, ok := v.Addr().Interface().(fmt.Stringer); ok {
+			value = s
+		}
+	}
+
+	if fmt.Sprint(value) != condValue {
+		return nil
+	}
+
+	return errors.Wrapf(c.Require(names...),
+		"%q is %q", condField, condValue)
+}
+
+// RequireScheme checks that a url.URL or copperhead.URL field is set
+// and that its scheme is one of the allowed schemes. Schemes are
+// compared case-insensitively.
+func (c *Config) RequireScheme(name string, schemes ...string) error {
+	if c.skipRequired() {
+		return nil
+	}
+
+	if _, ok := fieldByPath(c.obj.Type(), name, c.aliasTag()); !ok {
+		return errors.Errorf(
+			"unknown configuration field %q", name)
+	}
+
+	u, err := c.URL(name)
+	if err != nil {
+		if v, lerr := c.lookup(name); lerr != nil || isNilValue(v) {
+			return errors.Errorf("%q is missing", name)
+		}
+		return err
+	}
+
+	for _, scheme := range schemes {
+		if strings.EqualFold(u.Scheme, scheme) {
+			return nil
+		}
+	}
+
+	return errors.Errorf(
+		"%q must use one of the schemes %s, not %q",
+		name, quoteList(schemes), u.Scheme,
+	)
+}
+
+// isNilValue checks if a value is a nil pointer or interface.
+func isNilValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	}
+	return false
+}
+
+// RequireOneOf checks that exactly one of the named fields is set.
+// Fields are considered set using the same rules as Require, except
+// that booleans are set when true.
+func (c *Config) RequireOneOf(names ...string) error {
+	if c.skipRequired() {
+		return nil
+	}
+
+	set, err := c.setFields(names)
+	if err != nil {
+		return err
+	}
+
+	if len(set) != 1 {
+		return errors.Errorf(
+			"exactly one of %s must be set, %d were set",
+			quoteList(names), len(set),
+		)
+	}
+
+	return nil
+}
+
+// RequireMutuallyExclusive checks that at most one of the named
+// fields is set, see RequireOneOf.
+func (c *Config) RequireMutuallyExclusive(names ...string) error {
+	set, err := c.setFields(names)
+	if err != nil {
+		return err
+	}
+
+	if len(set) > 1 {
+		return errors.Errorf(
+			"at most one of %s can be set, %d were set: %s",
+			quoteList(names), len(set), quoteList(set),
+		)
+	}
+
+	return nil
+}
+
+// RequireAtLeast checks that at least n of the named fields are set,
+// see RequireOneOf.
+func (c *Config) RequireAtLeast(n int, names ...string) error {
+	if c.skipRequired() {
+		return nil
+	}
+
+	if n < 1 || n > len(names) {
+		return errors.Errorf(
+			"cannot require %d of %d fields", n, len(names))
+	}
+
+	set, err := c.setFields(names)
+	if err != nil {
+		return err
+	}
+
+	if len(set) < n {
+		return errors.Errorf(
+			"at least %d of %s must be set, %d were set",
+			n, quoteList(names), len(set),
+		)
+	}
+
+	return nil
+}
+
+// setFields returns the names of the fields that are set. Fields
+// behind nil pointers are treated as unset.
+func (c *Config) setFields(names []string) ([]string, error) {
+	if len(names) == 0 {
+		return nil, errors.New("no field names given")
+	}
+
+	var set []string
+
+	for _, name := range names {
+		if _, ok := fieldByPath(c.obj.Type(), name, c.aliasTag()); !ok {
+			return nil, errors.Errorf(
+				"unknown configuration field %q", name)
+		}
+
+		v, err := c.lookup(name)
+		if err != nil {
+			continue
+		}
+
+		isSet := checkRequired(name, v) == nil
+		if v.Kind() == reflect.Bool {
+			isSet = v.Bool()
+		}
+
+		if isSet {
+			set = append(set, name)
+		}
+	}
+
+	return set, nil
+}
+
+func quoteList(names []string) string {
+	quoted := make([]string, len(names))
+	for i := range names {
+		quoted[i] = strconv.Quote(names[i])
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// MustExist checks that the names resolve to configuration fields,
+// regardless of whether they're set. The names are checked against
+// the configuration type, so nil pointers along the path are left
+// as they are. All names that fail to resolve are reported in the
+// returned error.
+func (c *Config) MustExist(names ...string) error {
+	var unknown []string
+
+	for _, name := range names {
+		if _, ok := fieldByPath(c.obj.Type(), name, c.aliasTag()); !ok {
+			unknown = append(unknown, strconv.Quote(name))
+		}
+	}
+
+	if len(unknown) > 0 {
+		return errors.Errorf(
+			"unknown configuration fields: %s",
+			strings.Join(unknown, ", "),
+		)
+	}
+
+	return nil
+}
+
+// RequireDeep checks if configuration values are set, recursing
+// into nested structs to check that every field is set. All missing
+// fields are reported using their full dotted path.
+func (c *Config) RequireDeep(names ...string) error {
+	if c.skipRequired() {
+		return nil
+	}
+
+	var missing []string
+
+	for _, name := range names {
+		v, err := c.resolve(name)
+		if err != nil {
+			return errors.Wrapf(err,
+				"failed to resolve %q", name)
+		}
+
+		for _, m := range unsetFields(name, v, visited{}) {
+			missing = append(missing, strconv.Quote(m))
+		}
+	}
+
+	if len(missing) > 0 {
+		return errors.Errorf(
+			"missing required values: %s",
+			strings.Join(missing, ", "),
+		)
+	}
+
+	return nil
+}
+
+// unsetFields returns the dotted paths of all unset leaf values.
+// Pointers that already have been followed, as in cyclic values, are
+// considered to be set.
+func unsetFields(name string, v reflect.Value, seen visited) []string {
+	if v.Kind() == reflect.Ptr && !v.IsNil() &&
+		isMergeable(v.Type().Elem()) {
+		if !seen.visit(v) {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	if !isMergeable(v.Type()) {
+		if isUnset(v) {
+			return []string{name}
+		}
+		return nil
+	}
+
+	var missing []string
+	for i := 0; i < v.NumField(); i++ {
+		missing = append(missing, unsetFields(
+			name+"."+v.Type().Field(i).Name, v.Field(i), seen,
+		)...)
+	}
+
+	return missing
+}
+
+// isUnset checks if a value is unset. Booleans are always
+// considered to be set.
+func isUnset(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Bool:
+		return false
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	}
+
+	return v.IsZero()
+}
+
+func (c *Config) resolve(name string) (reflect.Value, error) {
+	return walk(c.obj, name, true, c.aliasTag())
+}
+
+// lookup resolves a field without populating nil pointers along
+// the path.
+func (c *Config) lookup(name string) (reflect.Value, error) {
+	return walk(c.obj, name, false, c.aliasTag())
+}
+
+func walk(root reflect.Value, name string, populate bool, aliasTag string) (reflect.Value, error) {
+	path := strings.Split(name, ".")
+
+	n := root
+	for len(path) > 0 {
+		head := path[0]
+		path = path[1:]
+
+		var field reflect.Value
+
+		switch n.Kind() {
+		case reflect.Slice, reflect.Array:
+			i, err := strconv.Atoi(head)
+			if err != nil || i < 0 {
+				return n, errors.Errorf(
+					"invalid index %q for a %q value",
+					head, n.Kind().String(),
+				)
+			}
+
+			if i >= n.Len() {
+				return n, errors.Errorf(
+					"index %d is out of range, the %q has %d elements",
+					i, n.Kind().String(), n.Len(),
+				)
+			}
+
+			field = n.Index(i)
+
+		case reflect.Struct:
+			sf, ok := findField(n.Type(), head, aliasTag)
+			if !ok {
+				return n, errors.Errorf(
+					"%q doesn't have a field %q",
+					n.Type().Name(), head,
+				)
+			}
+
+			f, err := fieldByIndex(n, sf.Index, populate)
+			if err != nil {
+				return n, errors.Wrapf(err,
+					"could not reach the promoted field %q", head)
+			}
+			field = f
+
+		default:
+			return n, errors.Errorf(
+				"cannot get field %q from a %q value",
+				head, n.Kind().String(),
+			)
+		}
+
+		if len(path) > 0 && !populate {
+			for field.Kind() == reflect.Ptr {
+				if field.IsNil() {
+					return n, errors.Errorf("%q is nil", head)
+				}
+				field = field.Elem()
+			}
+		} else if len(path) > 0 {
 			z, err := ensureZero(head, field)
 			if err != nil {
 				return n, err
@@ -298,25 +2014,73 @@ func (c *Config) resolve(name string) (reflect.Value, error) {
 	return n, nil
 }
 
-func ensureZero(name string, field reflect.Value) (*reflect.Value, error) {
-	// We attempt to populate nil pointers with zero
-	// values.
-	if field.Kind() == reflect.Ptr && field.IsNil() {
-		e := field.Type().Elem()
-		if e.Kind() == reflect.Ptr {
-			return nil, errors.Errorf(
-				"pointers to pointers (as in %q being a %q) are unsupported",
-				name, field.Type().String(),
-			)
+// fieldByIndex gets a possibly promoted field from a struct. Nil
+// pointers to embedded structs are populated with zero values if
+// populate is true.
+func fieldByIndex(v reflect.Value, index []int, populate bool) (reflect.Value, error) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			embedded := v.Type().Elem().Name()
+
+			if v.IsNil() && !populate {
+				return v, errors.Errorf(
+					"the embedded %q is nil", embedded)
+			}
+
+			if v.IsNil() && !v.CanSet() {
+				return v, errors.Errorf(
+					"the embedded %q is nil and unexported",
+					embedded)
+			}
+
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+
+			v = v.Elem()
 		}
 
-		zero := reflect.New(e)
-		field.Set(zero)
+		v = v.Field(x)
+	}
+
+	return v, nil
+}
+
+func ensureZero(name string, field reflect.Value) (*reflect.Value, error) {
+	t := field.Type()
+	if t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Ptr &&
+		t.Elem().Elem().Kind() == reflect.Ptr {
+		return nil, errors.Errorf(
+			"pointers to pointers to pointers (as in %q being a %q) are unsupported, use at most %q",
+			name, t.String(), "**"+baseType(t).String(),
+		)
 	}
 
-	if field.Kind() == reflect.Ptr {
+	// We attempt to populate nil pointers with zero values,
+	// allowing for one level of pointer to pointer. Slices and
+	// maps behind pointers are allocated as well.
+	for field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			field.Set(reflect.New(field.Type().Elem()))
+
+			switch e := field.Elem(); e.Kind() {
+			case reflect.Slice:
+				e.Set(reflect.MakeSlice(e.Type(), 0, 0))
+			case reflect.Map:
+				e.Set(reflect.MakeMap(e.Type()))
+			}
+		}
 		field = field.Elem()
 	}
 
 	return &field, nil
 }
+
+// baseType returns the type that t points to after all pointer
+// indirection.
+func baseType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
@@ -0,0 +1,42 @@
+package copperhead
+
+import (
+	"reflect"
+)
+
+// visited tracks the pointers that have been followed while
+// traversing a value, so that cyclic values, like a tree node that
+// points back at its parent, are only traversed once. The traversals
+// that copy values keep the copy of each pointer so that the copy
+// gets the same shape as the original.
+//
+// Traversals of types rather than values track the types that are on
+// the current path instead, see walkFields.
+type visited map[visitKey]reflect.Value
+
+type visitKey struct {
+	ptr uintptr
+	typ reflect.Type
+}
+
+// visit records that the pointer v has been followed, it returns
+// false if it already has been.
+func (vs visited) visit(v reflect.Value) bool {
+	key := visitKey{ptr: v.Pointer(), typ: v.Type()}
+	if _, ok := vs[key]; ok {
+		return false
+	}
+	vs[key] = v
+	return true
+}
+
+// copyOf returns the copy of the pointer v if it has been followed.
+func (vs visited) copyOf(v reflect.Value) (reflect.Value, bool) {
+	cp, ok := vs[visitKey{ptr: v.Pointer(), typ: v.Type()}]
+	return cp, ok
+}
+
+// setCopy records cp as the copy of the pointer v.
+func (vs visited) setCopy(v, cp reflect.Value) {
+	vs[visitKey{ptr: v.Pointer(), typ: v.Type()}] = cp
+}
@@ -0,0 +1,223 @@
+package copperhead
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// WithDefaults assigns the values of "default" tag options to unset
+// fields, see Config.Defaults.
+func WithDefaults() Option {
+	return func(c *Config) error {
+		return c.Defaults()
+	}
+}
+
+// Defaults assigns the values of "default" tag options to the fields
+// that are unset, which is usually done after all other sources have
+// been loaded. Fields that a source has set keep their value, even
+// if it's the zero value, so that a `default=true` boolean can be set
+// to false by a key in a configuration file. Other fields are unset
+// if they have their zero value, or if they implement Setter and
+// IsSet returns false.
+//
+// Defaults that start with "$" are computed defaults that copy the
+// value of another field, referenced by its dotted path:
+//
+//	BindAddr      string `copperhead:"default=:8080"`
+//	AdvertiseAddr string `copperhead:"default=$BindAddr"`
+//
+// References are resolved relative to the struct that contains the
+// field, falling back to paths from the root of the configuration.
+// The referenced field gets its own default first, and computed
+// defaults that reference each other in a cycle are errors. Use "$$"
+// for literal defaults that start with a "$".
+func (c *Config) Defaults() error {
+	if err := c.checkFrozen(); err != nil {
+		return err
+	}
+
+	d := defaulter{
+		c:        c,
+		defaults: make(map[string]string),
+		state:    make(map[string]defaultState),
+	}
+
+	var paths []string
+
+	walkFields(c.obj.Type(), func(path string, f reflect.StructField) {
+		tag := c.fieldTag(f)
+		if !tag.Has("default") {
+			return
+		}
+
+		paths = append(paths, path)
+		d.defaults[path] = tag.Get("default")
+	})
+
+	for _, path := range paths {
+		if err := d.apply(path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type defaultState int
+
+const (
+	defaultPending defaultState = iota
+	defaultApplying
+	defaultApplied
+)
+
+// defaulter applies defaults in dependency order.
+type defaulter struct {
+	c        *Config
+	defaults map[string]string
+	state    map[string]defaultState
+	chain    []string
+}
+
+func (d *defaulter) apply(path string) error {
+	switch d.state[path] {
+	case defaultApplied:
+		return nil
+	case defaultApplying:
+		return errors.Errorf("the computed defaults form a cycle: %s",
+			strings.Join(append(d.chain, path), " -> "))
+	}
+
+	d.state[path] = defaultApplying
+	d.chain = append(d.chain, path)
+
+	err := d.assign(path, d.defaults[path])
+
+	d.chain = d.chain[:len(d.chain)-1]
+	d.state[path] = defaultApplied
+
+	return err
+}
+
+func (d *defaulter) assign(path, def string) error {
+	c := d.c
+
+	ref := strings.HasPrefix(def, "$") && !strings.HasPrefix(def, "$$")
+	if !ref {
+		def = strings.TrimPrefix(def, "$")
+	}
+
+	var src reflect.Value
+
+	if ref {
+		refPath, ok := d.reference(path, def[1:])
+		if !ok {
+			return errors.Errorf(
+				"the default of %q references the unknown field %q",
+				path, def[1:])
+		}
+
+		if _, ok := d.defaults[refPath]; ok {
+			if err := d.apply(refPath); err != nil {
+				return err
+			}
+		}
+
+		// There is nothing to copy from unset or unreachable
+		// fields.
+		v, err := c.lookup(refPath)
+		if err != nil || c.isUnsetDefault(refPath, v) {
+			return nil
+		}
+		src = v
+	}
+
+	if v, err := c.lookup(path); err == nil && !c.isUnsetDefault(path, v) {
+		return nil
+	}
+
+	source := Source{Type: SourceDefault}
+	if allowed, err := c.CheckSource(path, source); !allowed {
+		return err
+	}
+
+	v, err := c.resolve(path)
+	if err != nil {
+		return errors.Wrapf(err,
+			"could not resolve %q", path)
+	}
+
+	if ref {
+		c.logf("assigning %s from the value of another field", path)
+		err = bindField(v, src, path)
+	} else {
+		c.logf("assigning %s from default: %s",
+			path, c.logValue(path, def))
+		err = c.assignField(path, v, def)
+	}
+	if err != nil {
+		return errors.Wrapf(err,
+			"could not assign the default of %q", path)
+	}
+
+	c.record(path, source)
+
+	return nil
+}
+
+// reference resolves the path of a field referenced by the default of
+// the field at path, relative to the struct that contains the field
+// first, and then from the root.
+func (d *defaulter) reference(path, ref string) (string, bool) {
+	t, aliasTag := d.c.obj.Type(), d.c.aliasTag()
+
+	if i := strings.LastIndex(path, "."); i != -1 {
+		sibling := path[:i+1] + ref
+		if _, ok := fieldByPath(t, sibling, aliasTag); ok {
+			return sibling, true
+		}
+	}
+
+	_, ok := fieldByPath(t, ref, aliasTag)
+	return ref, ok
+}
+
+// isUnsetDefault checks if the value of the field at path should get
+// its default. Fields that have been set by a source are set.
+func (c *Config) isUnsetDefault(path string, v reflect.Value) bool {
+	if len(c.provenance[path]) > 0 {
+		return false
+	}
+	return isUnsetValue(v)
+}
+
+// isUnsetValue checks if a value is unset. Unlike Require, false
+// booleans are unset.
+func isUnsetValue(v reflect.Value) bool {
+	if !v.CanInterface() {
+		return isZeroOrEmpty(v)
+	}
+
+	if set, ok := checkSetter(v); ok {
+		return !set
+	}
+
+	if v.Kind() == reflect.Ptr && !v.IsNil() {
+		if set, ok := checkSetter(v.Elem()); ok {
+			return !set
+		}
+	}
+
+	return isZeroOrEmpty(v)
+}
+
+func isZeroOrEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
@@ -0,0 +1,45 @@
+package copperhead
+
+// WithDeprecationWarnings calls warn whenever a field with the
+// "deprecated" tag option is set by a source. The tag value is passed
+// as the message:
+//
+//	OldPort int `copperhead:"deprecated='use Port instead'"`
+//
+// Values from configuration data only trigger a warning if they
+// change the value of the field.
+func WithDeprecationWarnings(warn func(field, msg string)) Option {
+	return func(c *Config) error {
+		c.deprecation = warn
+		return nil
+	}
+}
+
+func (c *Config) warnDeprecated(path string) {
+	if c.deprecation == nil || c.preflight != nil {
+		return
+	}
+
+	f, ok := fieldByPath(c.obj.Type(), path, c.aliasTag())
+	if !ok {
+		return
+	}
+
+	if msg := deprecationMessage(c.fieldTag(f)); msg != "" {
+		c.deprecation(path, msg)
+	}
+}
+
+// deprecationMessage returns the deprecation message of a field, or
+// an empty string if the field isn't deprecated.
+func deprecationMessage(tag fieldTag) string {
+	if !tag.Has("deprecated") {
+		return ""
+	}
+
+	if msg := tag.Get("deprecated"); msg != "" {
+		return msg
+	}
+
+	return "the field is deprecated"
+}
@@ -0,0 +1,151 @@
+package copperhead
+
+import (
+	"reflect"
+)
+
+// FieldChange describes a change of a field value.
+type FieldChange struct {
+	Path string
+	Old  interface{}
+	New  interface{}
+}
+
+// Diff applies the options to a copy of the configuration and
+// reports the fields that would change, the configuration itself is
+// left untouched. Fields that are unreachable because of nil
+// pointers are treated as having their zero value. The values of
+// secret fields are replaced with Redacted.
+func (c *Config) Diff(opts ...Option) ([]FieldChange, error) {
+	clone := c.clone()
+
+	for _, opt := range opts {
+		if err := opt(clone); err != nil {
+			return nil, err
+		}
+	}
+
+	var changes []FieldChange
+
+	// Configurations that aren't structs are compared as a whole.
+	if c.obj.Kind() != reflect.Struct {
+		oldVal, newVal := c.obj.Interface(), clone.obj.Interface()
+		if !reflect.DeepEqual(oldVal, newVal) {
+			changes = append(changes, FieldChange{
+				Old: oldVal,
+				New: newVal,
+			})
+		}
+		return changes, nil
+	}
+
+	walkFields(c.obj.Type(), func(path string, f reflect.StructField) {
+		oldVal := fieldValue(c, path, f.Type)
+		newVal := fieldValue(clone, path, f.Type)
+
+		if reflect.DeepEqual(oldVal, newVal) {
+			return
+		}
+
+		if c.fieldTag(f).Has("secret") {
+			oldVal, newVal = Redacted, Redacted
+		}
+
+		changes = append(changes, FieldChange{
+			Path: path,
+			Old:  oldVal,
+			New:  newVal,
+		})
+	})
+
+	return changes, nil
+}
+
+func fieldValue(c *Config, path string, t reflect.Type) interface{} {
+	return valueAt(c.obj, path, t, c.aliasTag())
+}
+
+// clone creates a copy of the configuration that is backed by a
+// deep copy of the configuration struct.
+func (c *Config) clone() *Config {
+	cc := *c
+	cc.frozen = reflect.Value{}
+
+	cc.obj = reflect.New(c.obj.Type()).Elem()
+	cc.obj.Set(deepCopy(c.obj))
+
+	cc.envVars = make(map[string][]string, len(c.envVars))
+	for k, v := range c.envVars {
+		cc.envVars[k] = v
+	}
+
+	cc.provenance = make(map[string][]Source, len(c.provenance))
+	for k, v := range c.provenance {
+		cc.provenance[k] = append([]Source(nil), v...)
+	}
+
+	return &cc
+}
+
+// deepCopy copies a value, following pointers, slices, and maps.
+// Unexported struct fields are copied shallowly. Pointers that are
+// reached more than once, as in cyclic values, are only copied once.
+func deepCopy(v reflect.Value) reflect.Value {
+	return deepCopyValue(v, visited{})
+}
+
+func deepCopyValue(v reflect.Value, seen visited) reflect.Value {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		if cp, ok := seen.copyOf(v); ok {
+			return cp
+		}
+		cp := reflect.New(v.Type().Elem())
+		seen.setCopy(v, cp)
+		cp.Elem().Set(deepCopyValue(v.Elem(), seen))
+		return cp
+
+	case reflect.Struct:
+		cp := reflect.New(v.Type()).Elem()
+		cp.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).PkgPath != "" {
+				continue
+			}
+			cp.Field(i).Set(deepCopyValue(v.Field(i), seen))
+		}
+		return cp
+
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		cp := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			cp.Index(i).Set(deepCopyValue(v.Index(i), seen))
+		}
+		return cp
+
+	case reflect.Array:
+		cp := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			cp.Index(i).Set(deepCopyValue(v.Index(i), seen))
+		}
+		return cp
+
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		cp := reflect.MakeMapWithSize(v.Type(), v.Len())
+		for _, key := range v.MapKeys() {
+			cp.SetMapIndex(key, deepCopyValue(v.MapIndex(key), seen))
+		}
+		return cp
+	}
+
+	return v
+}
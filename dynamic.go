@@ -0,0 +1,151 @@
+package copperhead
+
+import (
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// assigner resolves the field at name and returns a function that
+// assigns string values to it. Paths that continue past a dynamic
+// section, a map[string]interface{} field, assign to nested keys of
+// the map, see assignDynamic.
+func (c *Config) assigner(name string) (func(val string) error, error) {
+	if section, keys, ok := c.dynamicPath(name); ok {
+		return func(val string) error {
+			return c.assignDynamic(section, keys, val)
+		}, nil
+	}
+
+	v, err := c.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(val string) error {
+		return c.assignField(name, v, val)
+	}, nil
+}
+
+var dynamicType = reflect.TypeOf(map[string]interface{}{})
+
+// dynamicPath splits name into the path of a dynamic section and the
+// keys within it.
+func (c *Config) dynamicPath(name string) (section string, keys []string, ok bool) {
+	parts := strings.Split(name, ".")
+
+	for i := 1; i < len(parts); i++ {
+		path := strings.Join(parts[:i], ".")
+
+		f, ok := fieldByPath(c.obj.Type(), path, c.aliasTag())
+		if !ok {
+			return "", nil, false
+		}
+
+		if baseType(f.Type) == dynamicType {
+			return path, parts[i:], true
+		}
+	}
+
+	return "", nil, false
+}
+
+// assignDynamic assigns a value to the nested keys of a dynamic
+// section, creating the section and nested map[string]interface{}
+// values as needed. The type of the value is inferred, see
+// inferValue. Keys that already hold values other than maps can't be
+// used as sections.
+func (c *Config) assignDynamic(section string, keys []string, val string) error {
+	v, err := c.resolve(section)
+	if err != nil {
+		return err
+	}
+
+	z, err := ensureZero(section, v)
+	if err != nil {
+		return err
+	}
+
+	m := *z
+	if m.IsNil() {
+		m.Set(reflect.MakeMap(m.Type()))
+	}
+
+	for i, key := range keys[:len(keys)-1] {
+		path := section + "." + strings.Join(keys[:i+1], ".")
+
+		next := m.MapIndex(mapKey(m, key))
+		if next.IsValid() && next.Kind() == reflect.Interface {
+			next = next.Elem()
+		}
+
+		if !next.IsValid() || (next.Kind() == reflect.Map && next.IsNil()) {
+			if !dynamicType.AssignableTo(m.Type().Elem()) {
+				return errors.Errorf(
+					"cannot create the section %q in a %s",
+					path, m.Type().String())
+			}
+			next = reflect.MakeMap(dynamicType)
+			m.SetMapIndex(mapKey(m, key), next)
+		}
+
+		if next.Kind() != reflect.Map || next.Type().Key().Kind() != reflect.String {
+			return errors.Errorf(
+				"%q is a %s, not a section",
+				path, next.Type().String())
+		}
+
+		m = next
+	}
+
+	value := reflect.ValueOf(inferValue(val))
+	if !value.Type().AssignableTo(m.Type().Elem()) {
+		return errors.Errorf(
+			"cannot assign a %s to a %s",
+			value.Type().String(), m.Type().String())
+	}
+
+	m.SetMapIndex(mapKey(m, keys[len(keys)-1]), value)
+
+	return nil
+}
+
+// mapKey converts key to the key type of the string keyed map m.
+func mapKey(m reflect.Value, key string) reflect.Value {
+	return reflect.ValueOf(key).Convert(m.Type().Key())
+}
+
+var numberPattern = regexp.MustCompile(
+	`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+// inferValue infers the type of a value that is assigned to a
+// dynamic section:
+//
+//   - "true" and "false" are booleans
+//   - numbers in the JSON number format are float64 values, like the
+//     numbers that encoding/json decodes into interface{} values, so
+//     that a key gets the same type from JSON data and from the
+//     environment
+//   - everything else is a string, including quoted values and
+//     numbers with leading zeroes
+func inferValue(val string) interface{} {
+	switch val {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+
+	if !numberPattern.MatchString(val) {
+		return val
+	}
+
+	if f, err := strconv.ParseFloat(val, 64); err == nil {
+		return f
+	}
+
+	return val
+}
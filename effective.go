@@ -0,0 +1,299 @@
+package copperhead
+
+import (
+	"encoding"
+	"encoding/json"
+	"net/url"
+	"reflect"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// MarshalJSON implements json.Marshaler by emitting the effective
+// configuration, with fields tagged as secret redacted in the same
+// way as SaveRedacted. This makes it safe to expose the configuration
+// in f.ex. a debug endpoint. Values that don't have a useful JSON
+// form are rendered in their text form: url.URL values as their
+// string and time.Duration values as in "10s". Use UnredactedJSON to
+// include the values of secret fields.
+func (c *Config) MarshalJSON() ([]byte, error) {
+	return c.effectiveJSON(true)
+}
+
+// UnredactedJSON emits the effective configuration like MarshalJSON,
+// but without redacting secret fields.
+func (c *Config) UnredactedJSON() ([]byte, error) {
+	return c.effectiveJSON(false)
+}
+
+func (c *Config) effectiveJSON(redact bool) ([]byte, error) {
+	tc := textCopier{
+		key:      c.tagKey,
+		redact:   redact,
+		types:    make(map[reflect.Type]reflect.Type),
+		indexes:  make(map[reflect.Type][]int),
+		building: make(map[reflect.Type]bool),
+		copies:   make(map[visitKey]reflect.Value),
+	}
+
+	data, err := json.Marshal(tc.copy(c.obj).Interface())
+	return data, errors.Wrap(err, "failed to marshal configuration")
+}
+
+var (
+	stringType         = reflect.TypeOf("")
+	emptyInterfaceType = reflect.TypeOf((*interface{})(nil)).Elem()
+	jsonMarshalerType  = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType  = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// textCopier copies values into types built with reflect.StructOf
+// where time.Duration and url.URL values are replaced by strings and
+// secrets are redacted. The copies keep the field names and tags of
+// the original structs, so encoding/json marshals them the same way.
+type textCopier struct {
+	key    string
+	redact bool
+
+	// types maps the original types to their copy types, and
+	// indexes holds the indexes of the struct fields that are
+	// copied.
+	types   map[reflect.Type]reflect.Type
+	indexes map[reflect.Type][]int
+
+	// building holds the struct types that are being built, to
+	// break recursive types with interface{} values.
+	building map[reflect.Type]bool
+
+	copies map[visitKey]reflect.Value
+}
+
+func isMarshaler(t reflect.Type) bool {
+	return t.Implements(jsonMarshalerType) || t.Implements(textMarshalerType) ||
+		reflect.PtrTo(t).Implements(jsonMarshalerType) ||
+		reflect.PtrTo(t).Implements(textMarshalerType)
+}
+
+// typeOf returns the copy type of t.
+func (tc *textCopier) typeOf(t reflect.Type) reflect.Type {
+	if ct, ok := tc.types[t]; ok {
+		return ct
+	}
+
+	var ct reflect.Type
+
+	switch {
+	case t == durationType || t == urlType:
+		ct = stringType
+	case t.Kind() == reflect.Interface:
+		ct = emptyInterfaceType
+	case isMarshaler(t):
+		ct = t
+	case t.Kind() == reflect.Ptr:
+		ct = reflect.PtrTo(tc.typeOf(t.Elem()))
+	case t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8:
+		ct = reflect.SliceOf(tc.typeOf(t.Elem()))
+	case t.Kind() == reflect.Array:
+		ct = reflect.ArrayOf(t.Len(), tc.typeOf(t.Elem()))
+	case t.Kind() == reflect.Map:
+		ct = reflect.MapOf(t.Key(), tc.typeOf(t.Elem()))
+	case t.Kind() == reflect.Struct:
+		if tc.building[t] {
+			// Recursive references are copied as
+			// interface{} values, and aren't cached as the
+			// type is incomplete.
+			return emptyInterfaceType
+		}
+		ct = tc.structType(t)
+	default:
+		ct = t
+	}
+
+	tc.types[t] = ct
+
+	return ct
+}
+
+func (tc *textCopier) structType(t reflect.Type) reflect.Type {
+	tc.building[t] = true
+	defer delete(tc.building, t)
+
+	var (
+		fields  []reflect.StructField
+		indexes []int
+		names   = make(map[string]bool, t.NumField())
+	)
+
+	for i := 0; i < t.NumField(); i++ {
+		names[t.Field(i).Name] = true
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		// Like encoding/json we only look at the exported fields
+		// of unexported embedded structs.
+		if f.PkgPath != "" && (!f.Anonymous ||
+			f.Type.Kind() != reflect.Struct) {
+			continue
+		}
+
+		ct := tc.typeOf(f.Type)
+		anonymous := f.Anonymous
+
+		// reflect.StructOf can't embed types with methods.
+		if ct.NumMethod() > 0 || reflect.PtrTo(ct).NumMethod() > 0 {
+			anonymous = false
+		}
+
+		name := f.Name
+		if f.PkgPath != "" {
+			name = exportedName(name, names)
+		}
+
+		fields = append(fields, reflect.StructField{
+			Name:      name,
+			Type:      ct,
+			Tag:       f.Tag,
+			Anonymous: anonymous,
+		})
+		indexes = append(indexes, i)
+	}
+
+	tc.indexes[t] = indexes
+
+	return reflect.StructOf(fields)
+}
+
+// exportedName creates an exported field name for the unexported
+// field name, that doesn't collide with any of the names of the
+// struct. The name of an embedded field doesn't end up in the JSON
+// output, so it only has to be a valid and unique name.
+func exportedName(name string, names map[string]bool) string {
+	r, size := utf8.DecodeRuneInString(name)
+
+	exported := string(unicode.ToUpper(r)) + name[size:]
+	if !unicode.IsUpper(unicode.ToUpper(r)) {
+		exported = "X" + name
+	}
+
+	for names[exported] {
+		exported += "_"
+	}
+	names[exported] = true
+
+	return exported
+}
+
+// copy copies v into its copy type.
+func (tc *textCopier) copy(v reflect.Value) reflect.Value {
+	return tc.copyAs(v, tc.typeOf(v.Type()))
+}
+
+func (tc *textCopier) copyAs(v reflect.Value, ct reflect.Type) reflect.Value {
+	cp := reflect.New(ct).Elem()
+
+	switch {
+	case ct == emptyInterfaceType && v.Kind() != reflect.Interface:
+		cp.Set(tc.copy(v))
+		return cp
+	case v.Type() == durationType:
+		cp.SetString(time.Duration(v.Int()).String())
+		return cp
+	case v.Type() == urlType:
+		if v.CanInterface() {
+			u := v.Interface().(url.URL)
+			cp.SetString(u.String())
+		}
+		return cp
+	case ct == v.Type() && v.Kind() != reflect.Struct &&
+		v.Kind() != reflect.Interface:
+		setReadOnly(cp, v)
+		return cp
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return cp
+		}
+		key := visitKey{ptr: v.Pointer(), typ: ct}
+		if c, ok := tc.copies[key]; ok {
+			return c
+		}
+		p := reflect.New(ct.Elem())
+		tc.copies[key] = p
+		p.Elem().Set(tc.copyAs(v.Elem(), ct.Elem()))
+		cp.Set(p)
+
+	case reflect.Interface:
+		if !v.IsNil() {
+			cp.Set(tc.copy(v.Elem()))
+		}
+
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice {
+			if v.IsNil() {
+				return cp
+			}
+			cp.Set(reflect.MakeSlice(ct, v.Len(), v.Len()))
+		}
+		for i := 0; i < v.Len(); i++ {
+			cp.Index(i).Set(tc.copyAs(v.Index(i), ct.Elem()))
+		}
+
+	case reflect.Map:
+		if v.IsNil() {
+			return cp
+		}
+		cp.Set(reflect.MakeMapWithSize(ct, v.Len()))
+		for _, k := range v.MapKeys() {
+			key := reflect.New(ct.Key()).Elem()
+			setReadOnly(key, k)
+			cp.SetMapIndex(key, tc.copyAs(v.MapIndex(k), ct.Elem()))
+		}
+
+	case reflect.Struct:
+		if ct == v.Type() {
+			setReadOnly(cp, v)
+			return cp
+		}
+
+		for j, i := range tc.indexes[v.Type()] {
+			f := v.Type().Field(i)
+			if tc.redact && getFieldTag(tc.key, f).Has("secret") {
+				cp.Field(j).Set(redacted(ct.Field(j).Type))
+				continue
+			}
+			cp.Field(j).Set(tc.copyAs(v.Field(i), ct.Field(j).Type))
+		}
+	}
+
+	return cp
+}
+
+// setReadOnly assigns v to dst. Values that are reached through
+// unexported embedded structs can't be assigned directly, so basic
+// kinds are copied by value and other values are left as zero.
+func setReadOnly(dst, v reflect.Value) {
+	if v.CanInterface() {
+		dst.Set(v)
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		dst.SetBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		dst.SetInt(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		dst.SetUint(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		dst.SetFloat(v.Float())
+	case reflect.String:
+		dst.SetString(v.String())
+	}
+}
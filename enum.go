@@ -0,0 +1,107 @@
+package copperhead
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Enum is a TextUnmarshaler-aware value that is restricted to a set
+// of allowed values. Use NewEnum to create an Enum and assign it to a
+// configuration field before loading:
+//
+//	conf.Level = copperhead.NewEnum("low", "medium", "high")
+//
+// Values that aren't allowed fail with an error listing the allowed
+// values. Enum implements Setter, so an Enum that hasn't been
+// assigned a value is missing for Require and gets the value of its
+// "default" tag option from Defaults.
+type Enum[T ~string] struct {
+	value   T
+	allowed []T
+	fold    bool
+	set     bool
+}
+
+// NewEnum creates an unset Enum that accepts the allowed values.
+func NewEnum[T ~string](allowed ...T) Enum[T] {
+	return Enum[T]{allowed: allowed}
+}
+
+// CaseInsensitive returns a copy of the Enum that matches values
+// case-insensitively, the canonical form of the value is stored.
+func (e Enum[T]) CaseInsensitive() Enum[T] {
+	e.fold = true
+	return e
+}
+
+// Value returns the current value, or the zero value of T if the
+// Enum is unset.
+func (e Enum[T]) Value() T {
+	return e.value
+}
+
+// IsSet implements Setter.
+func (e Enum[T]) IsSet() bool {
+	return e.set
+}
+
+// Is checks if the current value is s.
+func (e Enum[T]) Is(s string) bool {
+	if e.fold {
+		return strings.EqualFold(string(e.value), s)
+	}
+	return string(e.value) == s
+}
+
+// String implements fmt.Stringer.
+func (e Enum[T]) String() string {
+	return string(e.value)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (e *Enum[T]) UnmarshalText(text []byte) error {
+	str := strings.TrimSpace(string(text))
+
+	if len(e.allowed) == 0 {
+		return errors.Errorf(
+			"cannot set the enum to %q, it has no allowed values, use NewEnum()",
+			str)
+	}
+
+	for _, a := range e.allowed {
+		if string(a) == str || (e.fold && strings.EqualFold(string(a), str)) {
+			e.value = a
+			e.set = true
+			return nil
+		}
+	}
+
+	allowed := make([]string, len(e.allowed))
+	for i := range e.allowed {
+		allowed[i] = string(e.allowed[i])
+	}
+
+	return errors.Errorf("invalid value %q, must be one of %s",
+		str, quoteList(allowed))
+}
+
+// UnmarshalJSON implements json.Unmarshaler, the value must be a
+// string. A JSON null leaves the value unchanged.
+func (e *Enum[T]) UnmarshalJSON(data []byte) error {
+	if isJSONNull(data) {
+		return nil
+	}
+
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return err
+	}
+	return e.UnmarshalText([]byte(str))
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (e Enum[T]) MarshalText() ([]byte, error) {
+	return []byte(e.value), nil
+}
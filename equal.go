@@ -0,0 +1,102 @@
+package copperhead
+
+import (
+	"net/url"
+	"reflect"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Equal compares the configuration field by field with other, which
+// is either a *Config or a value of, or a pointer to, the
+// configuration type. The paths of the fields that differ are
+// returned. URLs are compared by their string form, times using
+// time.Time.Equal, and other values using reflect.DeepEqual. Fields
+// that are unreachable because of nil pointers are treated as having
+// their zero value. Values of other types, and configurations that
+// aren't structs, are reported as differing at the empty path.
+func (c *Config) Equal(other interface{}) (bool, []string) {
+	ov, err := c.otherValue(other)
+	if err != nil {
+		return false, []string{""}
+	}
+
+	if c.obj.Kind() != reflect.Struct {
+		if !equalValues(c.obj.Interface(), ov.Interface()) {
+			return false, []string{""}
+		}
+		return true, nil
+	}
+
+	var diff []string
+
+	walkFields(c.obj.Type(), func(path string, f reflect.StructField) {
+		a := valueAt(c.obj, path, f.Type, c.aliasTag())
+		b := valueAt(ov, path, f.Type, c.aliasTag())
+
+		if !equalValues(a, b) {
+			diff = append(diff, path)
+		}
+	})
+
+	return len(diff) == 0, diff
+}
+
+func (c *Config) otherValue(other interface{}) (reflect.Value, error) {
+	if oc, ok := other.(*Config); ok {
+		other = oc.obj.Interface()
+	}
+
+	if other == nil {
+		return reflect.Value{}, errors.New("cannot compare with nil")
+	}
+
+	v := reflect.ValueOf(other)
+	if v.Kind() == reflect.Ptr && v.Type().Elem() == c.obj.Type() {
+		if v.IsNil() {
+			return reflect.Value{}, errors.New("cannot compare with a nil pointer")
+		}
+		v = v.Elem()
+	}
+
+	if v.Type() != c.obj.Type() {
+		return reflect.Value{}, errors.Errorf(
+			"cannot compare a %q with a %q",
+			c.obj.Type().String(), v.Type().String())
+	}
+
+	return v, nil
+}
+
+// equalValues compares two values of the same type.
+func equalValues(a, b interface{}) bool {
+	switch av := a.(type) {
+	case URL:
+		bv := b.(URL)
+		return av.String() == bv.String()
+	case *URL:
+		bv := b.(*URL)
+		if av == nil || bv == nil {
+			return av == bv
+		}
+		return av.String() == bv.String()
+	case url.URL:
+		bv := b.(url.URL)
+		return av.String() == bv.String()
+	case *url.URL:
+		bv := b.(*url.URL)
+		if av == nil || bv == nil {
+			return av == bv
+		}
+		return av.String() == bv.String()
+	case time.Time:
+		return av.Equal(b.(time.Time))
+	case Time:
+		return av.Equal(b.(Time).Time)
+	case LocalTime:
+		return av.Equal(b.(LocalTime).Time)
+	}
+
+	return reflect.DeepEqual(a, b)
+}
@@ -5,7 +5,6 @@ import (
 	"os"
 
 	"github.com/Sydsvenskan/copperhead"
-	yaml "gopkg.in/yaml.v2"
 )
 
 // Configuration is an example configuration struct
@@ -42,7 +41,7 @@ func ExampleConfig() {
 		copperhead.WithConfigurationFile(
 			"./test-data/example.conf.yaml",
 			copperhead.FileRequired,
-			copperhead.UnmarshalerFunc(yaml.Unmarshal),
+			copperhead.YAML,
 		),
 		copperhead.WithEnvironment(map[string]string{
 			"Name":         "APP_NAME",
@@ -76,7 +75,7 @@ func ExampleConfig() {
 
 	err = ch.File("./test-data/file-url.yaml",
 		copperhead.FileRequired,
-		copperhead.UnmarshalerFunc(yaml.Unmarshal))
+		copperhead.YAML)
 	if err != nil {
 		println(err.Error())
 		os.Exit(1)
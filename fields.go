@@ -0,0 +1,135 @@
+package copperhead
+
+import (
+	"reflect"
+	"strings"
+)
+
+// walkFields calls fn with the dotted path of every exported leaf
+// field of the struct type t, other types have no fields. Plain
+// structs, and pointers to them, are recursed into, while other types
+// are treated as leaves. Fields of embedded structs are listed by
+// their promoted names.
+//
+// Recursive types are followed once, a struct type that already is
+// being walked is treated as a leaf, so a Next *Node field of a Node
+// is listed as "Next" and not recursed into. The depth is limited by
+// the number of distinct struct types in the configuration.
+func walkFields(t reflect.Type, fn func(path string, f reflect.StructField)) {
+	if t.Kind() != reflect.Struct {
+		return
+	}
+
+	walkFieldsPrefix(t, "", map[reflect.Type]bool{}, fn)
+}
+
+func walkFieldsPrefix(
+	t reflect.Type, prefix string, seen map[reflect.Type]bool,
+	fn func(path string, f reflect.StructField),
+) {
+	// Guard against recursive types.
+	seen[t] = true
+	defer delete(seen, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		ft := f.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+
+		// Fields of embedded structs are promoted, so they're
+		// listed without the name of the embedded struct. The
+		// fields of unexported embedded structs are reachable as
+		// long as we don't have to go through a pointer.
+		if f.Anonymous && isMergeable(ft) && !seen[ft] &&
+			(f.PkgPath == "" || f.Type.Kind() != reflect.Ptr) {
+			walkFieldsPrefix(ft, prefix, seen, fn)
+			continue
+		}
+
+		if f.PkgPath != "" {
+			continue
+		}
+
+		path := prefix + f.Name
+
+		if isMergeable(ft) && !seen[ft] {
+			walkFieldsPrefix(ft, path+".", seen, fn)
+			continue
+		}
+
+		fn(path, f)
+	}
+}
+
+// FieldInfo describes a configuration field.
+type FieldInfo struct {
+	// Path is the dotted path of the field.
+	Path string
+	// Kind is the kind of the field.
+	Kind reflect.Kind
+	// Type is the name of the fields Go type.
+	Type string
+	// Env is the environment variable name from the "env" tag
+	// option.
+	Env string
+	// Default is the value from the "default" tag option.
+	Default string
+	// Required is true if the field has the "required" tag option.
+	Required bool
+	// Secret is true if the field has the "secret" tag option.
+	Secret bool
+	// Description is the documentation from the "desc" tag
+	// option. Multi-line descriptions have each line trimmed.
+	Description string
+	// Deprecated is the message from the "deprecated" tag option,
+	// empty if the field isn't deprecated.
+	Deprecated string
+	// Zero is true if the field currently has its zero value.
+	Zero bool
+}
+
+// Fields lists all the exported leaf fields of the configuration.
+// Nested structs are traversed, but cyclic types are only followed
+// once.
+func (c *Config) Fields() []FieldInfo {
+	var fields []FieldInfo
+
+	walkFields(c.obj.Type(), func(path string, f reflect.StructField) {
+		tag := c.fieldTag(f)
+
+		info := FieldInfo{
+			Path:     path,
+			Kind:     f.Type.Kind(),
+			Type:     f.Type.String(),
+			Env:      tag.Get("env"),
+			Default:  tag.Get("default"),
+			Required: tag.Has("required"),
+			Secret:   tag.Has("secret"),
+			Zero:     true,
+
+			Description: normalizeDescription(tag.Get("desc")),
+			Deprecated:  deprecationMessage(tag),
+		}
+
+		if v, err := c.lookup(path); err == nil {
+			info.Zero = v.IsZero()
+		}
+
+		fields = append(fields, info)
+	})
+
+	return fields
+}
+
+// normalizeDescription trims the whitespace of every line of a
+// description, and drops leading and trailing empty lines.
+func normalizeDescription(desc string) string {
+	lines := strings.Split(strings.TrimSpace(desc), "\n")
+	for i := range lines {
+		lines[i] = strings.TrimSpace(lines[i])
+	}
+	return strings.Join(lines, "\n")
+}
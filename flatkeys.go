@@ -0,0 +1,156 @@
+package copperhead
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// WithFlattenedKeys treats configuration documents as flat maps
+// where the keys are dotted field paths, as in
+// {"birdie.value": 12, "birdie.name": "Heron"}, see
+// Config.FlattenedKeys.
+func WithFlattenedKeys() Option {
+	return func(c *Config) error {
+		c.FlattenedKeys()
+		return nil
+	}
+}
+
+// FlattenedKeys treats configuration documents that are read after
+// the call as flat maps of dotted field paths to values. Field names
+// in the keys are matched case-insensitively, and every key is
+// assigned in the same way as with Set, with values that aren't
+// strings encoded as JSON. Null values leave the field unchanged.
+// Keys that don't resolve to a field are reported in the returned
+// error.
+func (c *Config) FlattenedKeys() {
+	c.flatKeys = true
+}
+
+func (c *Config) unmarshalFlat(name string, data []byte, unm Unmarshaler) error {
+	var flat map[string]interface{}
+	if err := unmarshalInto(name, data, unm, &flat); err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(flat))
+	for key := range flat {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var unknown []string
+
+	for _, key := range keys {
+		path := matchFieldNames(c.obj.Type(), key)
+
+		assign, err := c.assigner(path)
+		if err != nil {
+			unknown = append(unknown, fmt.Sprintf(
+				"%q (%s)", key, err.Error()))
+			continue
+		}
+
+		// Null values leave the field unchanged.
+		if flat[key] == nil {
+			continue
+		}
+
+		value, err := flatValue(flat[key])
+		if err != nil {
+			return errors.Wrapf(err,
+				"invalid value for %q", key)
+		}
+
+		c.logf("assigning %s: %s", path, c.logValue(path, value))
+
+		if err := assign(value); err != nil {
+			return errors.Wrapf(err,
+				"could not assign value to %q", key)
+		}
+	}
+
+	if len(unknown) > 0 {
+		return errors.Errorf(
+			"unknown configuration keys: %s",
+			strings.Join(unknown, ", "),
+		)
+	}
+
+	return nil
+}
+
+// matchFieldNames replaces the segments of a dotted key with the
+// names of the fields that they match case-insensitively, as field
+// names are matched when unmarshalling JSON.
+func matchFieldNames(t reflect.Type, key string) string {
+	segments := strings.Split(key, ".")
+
+	for i, seg := range segments {
+		t = baseType(t)
+
+		switch t.Kind() {
+		case reflect.Slice, reflect.Array:
+			t = t.Elem()
+			continue
+		case reflect.Struct:
+		default:
+			return strings.Join(segments, ".")
+		}
+
+		f, ok := t.FieldByNameFunc(func(name string) bool {
+			return strings.EqualFold(name, seg)
+		})
+		if !ok {
+			break
+		}
+
+		segments[i] = f.Name
+		t = f.Type
+	}
+
+	return strings.Join(segments, ".")
+}
+
+// flatValue converts a decoded value to the string representation
+// that assign expects.
+func flatValue(v interface{}) (string, error) {
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+
+	data, err := json.Marshal(stringKeys(v))
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+// stringKeys converts the maps with interface keys that YAML
+// decodes into maps with string keys, so that they can be encoded
+// as JSON.
+func stringKeys(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, val := range v {
+			m[fmt.Sprint(k)] = stringKeys(val)
+		}
+		return m
+	case map[string]interface{}:
+		for k, val := range v {
+			v[k] = stringKeys(val)
+		}
+	case []interface{}:
+		for i, val := range v {
+			v[i] = stringKeys(val)
+		}
+	}
+	return v
+}
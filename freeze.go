@@ -0,0 +1,68 @@
+package copperhead
+
+import (
+	"reflect"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// Freeze marks the configuration as loaded. After Freeze every
+// method that would change the configuration, like Set, Environment,
+// File, and Merge, returns an error, and CheckUnchanged can be used
+// to detect changes that were made to the struct directly.
+func (c *Config) Freeze() {
+	c.frozen = reflect.New(c.obj.Type()).Elem()
+	c.frozen.Set(deepCopy(c.obj))
+}
+
+// Frozen returns true if Freeze has been called.
+func (c *Config) Frozen() bool {
+	return c.frozen.IsValid()
+}
+
+// CheckUnchanged returns an error listing the fields that have
+// changed since Freeze was called. Values are deep copied by Freeze,
+// following pointers, slices, and maps, and are compared using
+// reflect.DeepEqual, so a pointer that has been replaced with one to
+// an equal value is unchanged. Unexported fields are not compared.
+func (c *Config) CheckUnchanged() error {
+	if !c.Frozen() {
+		return errors.New("the configuration hasn't been frozen")
+	}
+
+	if c.obj.Kind() != reflect.Struct {
+		if !reflect.DeepEqual(c.frozen.Interface(), c.obj.Interface()) {
+			return errors.New("the configuration has been modified")
+		}
+		return nil
+	}
+
+	var changed []string
+
+	walkFields(c.obj.Type(), func(path string, f reflect.StructField) {
+		oldVal := valueAt(c.frozen, path, f.Type, c.aliasTag())
+		newVal := valueAt(c.obj, path, f.Type, c.aliasTag())
+
+		if !reflect.DeepEqual(oldVal, newVal) {
+			changed = append(changed, path)
+		}
+	})
+
+	if len(changed) > 0 {
+		sort.Strings(changed)
+		return errors.Errorf(
+			"the configuration has been modified: %s",
+			quoteList(changed))
+	}
+
+	return nil
+}
+
+// checkFrozen returns an error if the configuration is frozen.
+func (c *Config) checkFrozen() error {
+	if c.Frozen() {
+		return errors.New("the configuration is frozen")
+	}
+	return nil
+}
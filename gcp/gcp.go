@@ -0,0 +1,320 @@
+// Package gcp loads configuration from Google Cloud Secret Manager
+// and Runtime Configurator.
+//
+// The package doesn't depend on the Google Cloud client libraries,
+// instead the clients are adapted to the SecretManagerClient and
+// RuntimeConfigClient interfaces:
+//
+//	type smClient struct {
+//		sm *secretmanager.Client
+//	}
+//
+//	func (c smClient) AccessSecretVersion(
+//		ctx context.Context, name string,
+//	) ([]byte, error) {
+//		resp, err := c.sm.AccessSecretVersion(ctx,
+//			&secretmanagerpb.AccessSecretVersionRequest{Name: name})
+//		if err != nil {
+//			return nil, err
+//		}
+//		return resp.Payload.Data, nil
+//	}
+//
+// Errors with gRPC status codes, and *googleapi.Error values with
+// HTTP status codes, are classified as ErrNotFound or
+// ErrPermissionDenied.
+package gcp
+
+import (
+	"context"
+	"reflect"
+	"strings"
+
+	"github.com/Sydsvenskan/copperhead"
+	"github.com/pkg/errors"
+)
+
+// SecretManagerClient reads secret versions from Secret Manager.
+type SecretManagerClient interface {
+	// AccessSecretVersion returns the payload of a secret
+	// version, name is the full resource name, as in
+	// "projects/my-project/secrets/db/versions/latest".
+	AccessSecretVersion(ctx context.Context, name string) ([]byte, error)
+}
+
+// RuntimeConfigClient lists variables from Runtime Configurator.
+type RuntimeConfigClient interface {
+	// ListVariables returns the variables of a config with
+	// their values, parent is the full resource name of the
+	// config, as in "projects/my-project/configs/myapp".
+	ListVariables(ctx context.Context, parent string) ([]Variable, error)
+}
+
+// Variable is a Runtime Configurator variable.
+type Variable struct {
+	// Name is the full resource name of the variable, as in
+	// "projects/my-project/configs/myapp/variables/database/port".
+	Name string
+	// Value is the value of the variable, either Value or Text
+	// is set.
+	Value []byte
+	Text  string
+}
+
+// The kinds of errors that can occur when configuration is fetched,
+// use errors.Is() to check the kind of an error.
+var (
+	ErrNotFound         = errors.New("not found")
+	ErrPermissionDenied = errors.New("permission denied")
+)
+
+// Error is returned when configuration can't be fetched.
+type Error struct {
+	// Name is the resource name of the secret or config.
+	Name string
+	// Kind is ErrNotFound, ErrPermissionDenied, or nil for other
+	// errors.
+	Kind error
+	Err  error
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	msg := `failed to fetch "` + e.Name + `"`
+	if e.Kind != nil {
+		msg += ": " + e.Kind.Error()
+	}
+	return msg + ": " + e.Err.Error()
+}
+
+// Is reports whether target is the kind of the error.
+func (e *Error) Is(target error) bool {
+	return e.Kind != nil && target == e.Kind
+}
+
+// Cause returns the underlying error.
+func (e *Error) Cause() error {
+	return e.Err
+}
+
+// WithSecretManager reads configuration from the latest versions of
+// secrets containing JSON blobs, see SecretManager.
+func WithSecretManager(
+	ctx context.Context, client SecretManagerClient,
+	projectID string, names ...string,
+) copperhead.Option {
+	return func(c *copperhead.Config) error {
+		return SecretManager(ctx, c, client, projectID, names...)
+	}
+}
+
+// WithSecretManagerFields assigns the latest versions of secrets to
+// fields, see SecretManagerFields.
+func WithSecretManagerFields(
+	ctx context.Context, client SecretManagerClient,
+	projectID string, fields map[string]string,
+) copperhead.Option {
+	return func(c *copperhead.Config) error {
+		return SecretManagerFields(ctx, c, client, projectID, fields)
+	}
+}
+
+// WithRuntimeConfig assigns the variables of a runtime config to
+// fields, see RuntimeConfig.
+func WithRuntimeConfig(
+	ctx context.Context, client RuntimeConfigClient,
+	projectID, config string,
+) copperhead.Option {
+	return func(c *copperhead.Config) error {
+		return RuntimeConfig(ctx, c, client, projectID, config)
+	}
+}
+
+// SecretManager reads configuration from the latest versions of
+// secrets containing JSON blobs, in order.
+func SecretManager(
+	ctx context.Context, c *copperhead.Config,
+	client SecretManagerClient, projectID string, names ...string,
+) error {
+	if c.Preflight() {
+		return nil
+	}
+
+	for _, name := range names {
+		data, err := accessSecret(ctx, client, projectID, name)
+		if err != nil {
+			return err
+		}
+
+		if err := c.Data(data, nil); err != nil {
+			return errors.Wrapf(err,
+				"failed to apply the secret %q", name)
+		}
+	}
+
+	return nil
+}
+
+// SecretManagerFields assigns the latest versions of secrets to
+// fields, the fields map is keyed by field name and has secret names
+// as values.
+func SecretManagerFields(
+	ctx context.Context, c *copperhead.Config,
+	client SecretManagerClient, projectID string, fields map[string]string,
+) error {
+	if c.Preflight() {
+		return nil
+	}
+
+	for field, name := range fields {
+		data, err := accessSecret(ctx, client, projectID, name)
+		if err != nil {
+			return err
+		}
+
+		if err := c.Set(field, string(data)); err != nil {
+			return errors.Wrapf(err,
+				"failed to assign the secret %q", name)
+		}
+	}
+
+	return nil
+}
+
+// RuntimeConfig assigns the variables of a runtime config to fields.
+// Variable names are paths where "/" separates the field names, so
+// the variable "database/port" is assigned to "database.port".
+// Variable names that don't resolve to a field are errors.
+func RuntimeConfig(
+	ctx context.Context, c *copperhead.Config,
+	client RuntimeConfigClient, projectID, config string,
+) error {
+	if c.Preflight() {
+		return nil
+	}
+
+	parent := "projects/" + projectID + "/configs/" + config
+
+	variables, err := client.ListVariables(ctx, parent)
+	if err != nil {
+		return &Error{Name: parent, Kind: classify(err), Err: err}
+	}
+
+	prefix := parent + "/variables/"
+
+	for _, v := range variables {
+		path := strings.Replace(
+			strings.TrimPrefix(v.Name, prefix), "/", ".", -1)
+
+		value := v.Text
+		if v.Value != nil {
+			value = string(v.Value)
+		}
+
+		if err := c.Set(path, value); err != nil {
+			return errors.Wrapf(err,
+				"failed to assign the variable %q", v.Name)
+		}
+	}
+
+	return nil
+}
+
+func accessSecret(
+	ctx context.Context, client SecretManagerClient,
+	projectID, name string,
+) ([]byte, error) {
+	resource := "projects/" + projectID + "/secrets/" + name + "/versions/latest"
+
+	data, err := client.AccessSecretVersion(ctx, resource)
+	if err != nil {
+		return nil, &Error{Name: resource, Kind: classify(err), Err: err}
+	}
+
+	return data, nil
+}
+
+// gRPC status codes.
+const (
+	codeNotFound         = 5
+	codePermissionDenied = 7
+	codeUnauthenticated  = 16
+)
+
+// classify maps gRPC and HTTP status codes to our error kinds.
+func classify(err error) error {
+	for err != nil {
+		if err == ErrNotFound || err == ErrPermissionDenied {
+			return err
+		}
+
+		switch grpcCode(err) {
+		case codeNotFound:
+			return ErrNotFound
+		case codePermissionDenied, codeUnauthenticated:
+			return ErrPermissionDenied
+		}
+
+		switch httpCode(err) {
+		case 404:
+			return ErrNotFound
+		case 401, 403:
+			return ErrPermissionDenied
+		}
+
+		switch e := err.(type) {
+		case interface{ Unwrap() error }:
+			err = e.Unwrap()
+		case interface{ Cause() error }:
+			err = e.Cause()
+		default:
+			err = nil
+		}
+	}
+
+	return nil
+}
+
+// grpcCode gets the code of the status returned by the GRPCStatus()
+// method of gRPC errors, or -1 if there is none.
+func grpcCode(err error) int64 {
+	m := reflect.ValueOf(err).MethodByName("GRPCStatus")
+	if !m.IsValid() || m.Type().NumIn() != 0 || m.Type().NumOut() != 1 {
+		return -1
+	}
+
+	status := m.Call(nil)[0]
+	if status.Kind() == reflect.Ptr && status.IsNil() {
+		return -1
+	}
+
+	code := status.MethodByName("Code")
+	if !code.IsValid() || code.Type().NumIn() != 0 || code.Type().NumOut() != 1 {
+		return -1
+	}
+
+	switch v := code.Call(nil)[0]; v.Kind() {
+	case reflect.Uint32, reflect.Uint64, reflect.Uint:
+		return int64(v.Uint())
+	case reflect.Int32, reflect.Int64, reflect.Int:
+		return v.Int()
+	}
+
+	return -1
+}
+
+// httpCode gets the value of the Code field of googleapi.Error, or 0
+// if there is none.
+func httpCode(err error) int64 {
+	v := reflect.Indirect(reflect.ValueOf(err))
+	if v.Kind() != reflect.Struct {
+		return 0
+	}
+
+	f := v.FieldByName("Code")
+	if !f.IsValid() || f.Kind() != reflect.Int {
+		return 0
+	}
+
+	return f.Int()
+}
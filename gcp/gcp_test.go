@@ -0,0 +1,129 @@
+package gcp_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Sydsvenskan/copperhead"
+	"github.com/Sydsvenskan/copperhead/gcp"
+)
+
+// fakeStatus mimics a gRPC status.
+type fakeStatus struct {
+	code uint32
+}
+
+func (s *fakeStatus) Code() uint32 { return s.code }
+
+// grpcError mimics the errors returned by gRPC clients.
+type grpcError struct {
+	status *fakeStatus
+}
+
+func (e grpcError) Error() string           { return "rpc error" }
+func (e grpcError) GRPCStatus() *fakeStatus { return e.status }
+
+func newGRPCError(code uint32) error {
+	return grpcError{&fakeStatus{code}}
+}
+
+type fakeSecrets map[string]string
+
+func (fc fakeSecrets) secret(name string) string {
+	return "projects/p/secrets/" + name + "/versions/latest"
+}
+
+func (fc fakeSecrets) AccessSecretVersion(
+	ctx context.Context, name string,
+) ([]byte, error) {
+	switch name {
+	case fc.secret("denied"):
+		return nil, newGRPCError(7)
+	}
+
+	value, ok := fc[name]
+	if !ok {
+		return nil, newGRPCError(5)
+	}
+	return []byte(value), nil
+}
+
+type fakeRuntimeConfig []gcp.Variable
+
+func (fc fakeRuntimeConfig) ListVariables(
+	ctx context.Context, parent string,
+) ([]gcp.Variable, error) {
+	if parent != "projects/p/configs/myapp" {
+		return nil, newGRPCError(5)
+	}
+	return fc, nil
+}
+
+type appConf struct {
+	User     string
+	Password string
+	Database struct {
+		Port int
+	}
+}
+
+func TestSecretManager(t *testing.T) {
+	client := fakeSecrets{}
+	client[client.secret("db")] = `{"User":"admin"}`
+	client[client.secret("db-password")] = "hunter2"
+
+	var conf appConf
+	err := copperhead.Configure(&conf,
+		gcp.WithSecretManager(context.Background(), client, "p", "db"),
+		gcp.WithSecretManagerFields(context.Background(), client, "p",
+			map[string]string{"Password": "db-password"}),
+	)
+	if err != nil {
+		t.Fatal("failed to load secrets: " + err.Error())
+	}
+
+	if conf.User != "admin" || conf.Password != "hunter2" {
+		t.Errorf("unexpected configuration %#v", conf)
+	}
+
+	cases := map[string]error{
+		"missing": gcp.ErrNotFound,
+		"denied":  gcp.ErrPermissionDenied,
+	}
+
+	for name, kind := range cases {
+		err := copperhead.Configure(&appConf{},
+			gcp.WithSecretManager(context.Background(), client, "p", name),
+		)
+		if !errors.Is(err, kind) {
+			t.Errorf("expected %q to fail with %q, got: %v", name, kind, err)
+		}
+	}
+}
+
+func TestRuntimeConfig(t *testing.T) {
+	client := fakeRuntimeConfig{
+		{Name: "projects/p/configs/myapp/variables/User", Text: "admin"},
+		{Name: "projects/p/configs/myapp/variables/Database/Port", Value: []byte("5432")},
+	}
+
+	var conf appConf
+	err := copperhead.Configure(&conf,
+		gcp.WithRuntimeConfig(context.Background(), client, "p", "myapp"),
+	)
+	if err != nil {
+		t.Fatal("failed to load variables: " + err.Error())
+	}
+
+	if conf.User != "admin" || conf.Database.Port != 5432 {
+		t.Errorf("unexpected configuration %#v", conf)
+	}
+
+	err = copperhead.Configure(&conf,
+		gcp.WithRuntimeConfig(context.Background(), client, "p", "other"),
+	)
+	if !errors.Is(err, gcp.ErrNotFound) {
+		t.Errorf("expected a not found error, got: %v", err)
+	}
+}
@@ -0,0 +1,133 @@
+package copperhead
+
+import (
+	"math"
+	"net/url"
+	"reflect"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	durationType       = reflect.TypeOf(time.Duration(0))
+	copperDurationType = reflect.TypeOf(Duration{})
+	copperURLType      = reflect.TypeOf(URL{})
+)
+
+// Int reads an integer configuration value. Unsigned values that
+// are too large for an int64 are errors.
+func (c *Config) Int(name string) (int64, error) {
+	v, err := c.resolveValue(name)
+	if err != nil {
+		return 0, err
+	}
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16,
+		reflect.Int32, reflect.Int64:
+		return v.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16,
+		reflect.Uint32, reflect.Uint64:
+		if v.Uint() > math.MaxInt64 {
+			return 0, errors.Errorf(
+				"the value %d of %q is out of range for an int64",
+				v.Uint(), name)
+		}
+		return int64(v.Uint()), nil
+	}
+
+	return 0, kindMismatch(name, "int", v)
+}
+
+// String reads a string configuration value.
+func (c *Config) String(name string) (string, error) {
+	v, err := c.resolveValue(name)
+	if err != nil {
+		return "", err
+	}
+
+	if v.Kind() != reflect.String {
+		return "", kindMismatch(name, "string", v)
+	}
+
+	return v.String(), nil
+}
+
+// Bool reads a boolean configuration value.
+func (c *Config) Bool(name string) (bool, error) {
+	v, err := c.resolveValue(name)
+	if err != nil {
+		return false, err
+	}
+
+	if v.Kind() != reflect.Bool {
+		return false, kindMismatch(name, "bool", v)
+	}
+
+	return v.Bool(), nil
+}
+
+// Duration reads a duration configuration value, the field must be
+// either a time.Duration or a copperhead.Duration.
+func (c *Config) Duration(name string) (time.Duration, error) {
+	v, err := c.resolveValue(name)
+	if err != nil {
+		return 0, err
+	}
+
+	switch v.Type() {
+	case durationType:
+		return time.Duration(v.Int()), nil
+	case copperDurationType:
+		return v.Interface().(Duration).Duration, nil
+	}
+
+	return 0, kindMismatch(name, "duration", v)
+}
+
+// URL reads a URL configuration value, the field must be either a
+// url.URL or a copperhead.URL. The returned URL is a copy.
+func (c *Config) URL(name string) (*url.URL, error) {
+	v, err := c.resolveValue(name)
+	if err != nil {
+		return nil, err
+	}
+
+	switch v.Type() {
+	case urlType:
+		u := v.Interface().(url.URL)
+		return &u, nil
+	case copperURLType:
+		u := v.Interface().(URL).URL
+		return &u, nil
+	}
+
+	return nil, kindMismatch(name, "URL", v)
+}
+
+// resolveValue resolves a field and dereferences any pointers
+// without populating them.
+func (c *Config) resolveValue(name string) (reflect.Value, error) {
+	v, err := c.lookup(name)
+	if err != nil {
+		return v, errors.Wrapf(err,
+			"could not resolve %q", name)
+	}
+
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return v, errors.Errorf("%q is nil", name)
+		}
+		v = v.Elem()
+	}
+
+	return v, nil
+}
+
+func kindMismatch(name, expected string, v reflect.Value) error {
+	return errors.Errorf(
+		"%q is a %q, not a %s",
+		name, v.Type().String(), expected,
+	)
+}
@@ -1,8 +1,13 @@
 module github.com/Sydsvenskan/copperhead
 
+go 1.18
+
 require (
-	github.com/kr/pretty v0.1.0 // indirect
 	github.com/pkg/errors v0.8.0
-	gopkg.in/check.v1 v1.0.0-20180628173108-788fd7840127 // indirect
 	gopkg.in/yaml.v2 v2.2.1
 )
+
+require (
+	github.com/kr/pretty v0.1.0 // indirect
+	gopkg.in/check.v1 v1.0.0-20180628173108-788fd7840127 // indirect
+)
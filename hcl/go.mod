@@ -0,0 +1,22 @@
+module github.com/Sydsvenskan/copperhead/hcl
+
+go 1.18
+
+require (
+	github.com/Sydsvenskan/copperhead v0.0.0-20261016164258-50a7753edb8c
+	github.com/hashicorp/hcl/v2 v2.23.0
+	github.com/pkg/errors v0.8.0
+)
+
+require (
+	github.com/agext/levenshtein v1.2.1 // indirect
+	github.com/apparentlymart/go-textseg/v15 v15.0.0 // indirect
+	github.com/google/go-cmp v0.6.0 // indirect
+	github.com/kr/text v0.1.0 // indirect
+	github.com/mitchellh/go-wordwrap v1.0.1 // indirect
+	github.com/zclconf/go-cty v1.16.4 // indirect
+	golang.org/x/mod v0.16.0 // indirect
+	golang.org/x/text v0.14.0 // indirect
+	golang.org/x/tools v0.18.0 // indirect
+	gopkg.in/yaml.v2 v2.2.1 // indirect
+)
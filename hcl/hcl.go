@@ -0,0 +1,55 @@
+// Package hcl provides an HCL unmarshaler for copperhead, for
+// configuration that is shared with Terraform style tooling:
+//
+//	err := copperhead.Configure(&conf,
+//		copperhead.WithConfigurationFile(
+//			"config.hcl", copperhead.FileRequired, hcl.Unmarshaler,
+//		),
+//	)
+//
+// Attributes and blocks are mapped to struct fields using "hcl"
+// struct tags, as in `hcl:"name"` for attributes and
+// `hcl:"server,block"` for blocks, see the gohcl package for
+// details.
+//
+// The package is a module of its own, so that copperhead doesn't
+// depend on HCL unless it's used.
+package hcl
+
+import (
+	"github.com/Sydsvenskan/copperhead"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/pkg/errors"
+)
+
+// Unmarshaler decodes HCL configuration. It's a
+// copperhead.ContextualUnmarshaler so errors refer to the name of the
+// configuration file.
+var Unmarshaler copperhead.Unmarshaler = unmarshaler{}
+
+type unmarshaler struct{}
+
+func (unmarshaler) Unmarshal(data []byte, v interface{}) error {
+	return Unmarshal("config.hcl", data, v)
+}
+
+func (unmarshaler) UnmarshalContext(name string, data []byte, v interface{}) error {
+	return Unmarshal(name, data, v)
+}
+
+// Unmarshal decodes HCL data into v. The filename is used in error
+// messages, which include the line and column of the problem.
+func Unmarshal(filename string, data []byte, v interface{}) error {
+	file, diags := hclparse.NewParser().ParseHCL(data, filename)
+	if diags.HasErrors() {
+		return errors.Wrap(diags, "invalid HCL")
+	}
+
+	diags = gohcl.DecodeBody(file.Body, nil, v)
+	if diags.HasErrors() {
+		return errors.Wrap(diags, "failed to decode HCL")
+	}
+
+	return nil
+}
@@ -0,0 +1,84 @@
+package hcl_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Sydsvenskan/copperhead"
+	"github.com/Sydsvenskan/copperhead/hcl"
+)
+
+type server struct {
+	Name string `hcl:"name,label"`
+	Host string `hcl:"host"`
+	Port int    `hcl:"port,optional"`
+}
+
+type conf struct {
+	Name    string   `hcl:"name"`
+	Tags    []string `hcl:"tags,optional"`
+	Servers []server `hcl:"server,block"`
+}
+
+func TestUnmarshal(t *testing.T) {
+	var c conf
+
+	err := copperhead.Configure(&c,
+		copperhead.WithConfigurationData([]byte(`
+name = "app"
+tags = ["a", "b"]
+
+server "one" {
+  host = "one.example.com"
+  port = 8080
+}
+
+server "two" {
+  host = "two.example.com"
+}
+`), hcl.Unmarshaler),
+		copperhead.RequireEach("Servers", "Name", "Host"),
+	)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if c.Name != "app" || len(c.Tags) != 2 || len(c.Servers) != 2 ||
+		c.Servers[0].Port != 8080 || c.Servers[1].Name != "two" {
+		t.Errorf("unexpected config %#v", c)
+	}
+}
+
+func TestErrorPosition(t *testing.T) {
+	dir, err := ioutil.TempDir("", "copperhead")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "config.hcl")
+
+	for data, expected := range map[string]string{
+		"name = \"app\"\ntags = [\n": "config.hcl:3,1",
+		"name = 12\nport = 1\n":      "config.hcl:2,1-5",
+	} {
+		err := ioutil.WriteFile(filename, []byte(data), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var c conf
+
+		err = copperhead.Configure(&c,
+			copperhead.WithConfigurationFile(
+				filename, copperhead.FileRequired, hcl.Unmarshaler),
+		)
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected error containing %q, got: %v",
+				expected, err)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package copperhead
+
+import (
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// WithInterpolation expands ${VAR} and $VAR references to
+// environment variables in all string fields. Unset variables
+// expand to an empty string. It should be given once, after the
+// sources, see Config.Interpolate.
+func WithInterpolation() Option {
+	return func(c *Config) error {
+		return c.Interpolate(false)
+	}
+}
+
+// WithStrictInterpolation expands ${VAR} and $VAR references to
+// environment variables in all string fields, and fails if a
+// referenced variable is unset. It should be given once, after the
+// sources, see Config.Interpolate.
+func WithStrictInterpolation() Option {
+	return func(c *Config) error {
+		return c.Interpolate(true)
+	}
+}
+
+// Interpolate expands ${VAR} and $VAR references to environment
+// variables in all string fields of the configuration, "$$" expands
+// to a literal "$". If strict is true an error is returned when a
+// referenced variable is unset, otherwise it expands to an empty
+// string.
+//
+// Interpolation isn't idempotent, as the "$$" escapes are consumed:
+// "$$5" expands to "$5", which a second pass would treat as a
+// reference to the variable "5". Interpolate once, after all sources
+// have been loaded.
+func (c *Config) Interpolate(strict bool) error {
+	if err := c.checkFrozen(); err != nil {
+		return err
+	}
+
+	var missing []string
+
+	expand := func(s string) string {
+		return os.Expand(s, func(name string) string {
+			if name == "$" {
+				return "$"
+			}
+
+			val, ok := os.LookupEnv(name)
+			if !ok {
+				missing = append(missing, name)
+			}
+			return val
+		})
+	}
+
+	interpolateValue(c.obj, expand, map[uintptr]bool{})
+
+	if strict && len(missing) > 0 {
+		sort.Strings(missing)
+		return errors.Errorf(
+			"unset environment variables referenced in configuration: %s",
+			strings.Join(missing, ", "),
+		)
+	}
+
+	return nil
+}
+
+func interpolateValue(
+	v reflect.Value, expand func(string) string,
+	seen map[uintptr]bool,
+) {
+	switch v.Kind() {
+	case reflect.String:
+		if v.CanSet() {
+			v.SetString(expand(v.String()))
+		}
+	case reflect.Ptr:
+		if v.IsNil() || seen[v.Pointer()] {
+			return
+		}
+		seen[v.Pointer()] = true
+		interpolateValue(v.Elem(), expand, seen)
+	case reflect.Struct:
+		pt := reflect.PtrTo(v.Type())
+		if pt.Implements(textUnmarshalerType) ||
+			pt.Implements(binaryUnmarshalerType) {
+			return
+		}
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).PkgPath != "" {
+				continue
+			}
+			interpolateValue(v.Field(i), expand, seen)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			interpolateValue(v.Index(i), expand, seen)
+		}
+	case reflect.Map:
+		if v.Type().Elem().Kind() != reflect.String {
+			return
+		}
+		for _, key := range v.MapKeys() {
+			val := v.MapIndex(key)
+			expanded := reflect.New(val.Type()).Elem()
+			expanded.SetString(expand(val.String()))
+			v.SetMapIndex(key, expanded)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package copperhead
+
+import (
+	"encoding/json"
+)
+
+// JSONC is an Unmarshaler for JSON with comments. Both line and
+// block comments are supported, as well as trailing commas in
+// objects and arrays. Stripped content is replaced with spaces so
+// that offsets and line numbers in errors are preserved.
+var JSONC = UnmarshalerFunc(func(data []byte, v interface{}) error {
+	return json.Unmarshal(stripJSONC(data), v)
+})
+
+// stripJSONC blanks out comments and trailing commas in a copy
+// of data.
+func stripJSONC(data []byte) []byte {
+	out := make([]byte, len(data))
+	copy(out, data)
+
+	var (
+		inString     bool
+		lastComma    = -1
+		lineComment  bool
+		blockComment bool
+	)
+
+	for i := 0; i < len(out); i++ {
+		b := out[i]
+
+		switch {
+		case lineComment:
+			if b == '\n' {
+				lineComment = false
+			} else {
+				out[i] = ' '
+			}
+		case blockComment:
+			if b == '*' && i+1 < len(out) && out[i+1] == '/' {
+				out[i], out[i+1] = ' ', ' '
+				blockComment = false
+				i++
+			} else if b != '\n' {
+				out[i] = ' '
+			}
+		case inString:
+			if b == '\\' {
+				i++
+			} else if b == '"' {
+				inString = false
+			}
+		case b == '/' && i+1 < len(out) && out[i+1] == '/':
+			out[i], out[i+1] = ' ', ' '
+			lineComment = true
+			i++
+		case b == '/' && i+1 < len(out) && out[i+1] == '*':
+			out[i], out[i+1] = ' ', ' '
+			blockComment = true
+			i++
+		case b == '"':
+			inString = true
+			lastComma = -1
+		case b == ',':
+			lastComma = i
+		case b == ']' || b == '}':
+			if lastComma >= 0 {
+				out[lastComma] = ' '
+			}
+			lastComma = -1
+		case b == ' ' || b == '\t' || b == '\r' || b == '\n':
+		default:
+			lastComma = -1
+		}
+	}
+
+	return out
+}
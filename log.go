@@ -0,0 +1,61 @@
+package copperhead
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+func (c *Config) logf(format string, args ...interface{}) {
+	if c.logger == nil || c.preflight != nil {
+		return
+	}
+	c.logger(format, args...)
+}
+
+// logValue formats a value for logging, redacting the values of
+// secret fields.
+func (c *Config) logValue(name, value string) string {
+	if c.logger == nil {
+		return ""
+	}
+
+	if f, ok := fieldByPath(c.obj.Type(), name, c.aliasTag()); ok &&
+		c.fieldTag(f).Has("secret") {
+		return Redacted
+	}
+
+	return strconv.Quote(value)
+}
+
+// fieldByPath finds the struct field for a dotted path.
+func fieldByPath(t reflect.Type, name string, aliasTag string) (reflect.StructField, bool) {
+	var sf reflect.StructField
+
+	for _, head := range strings.Split(name, ".") {
+		t = baseType(t)
+
+		// Slice indexes don't change the field.
+		if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			if _, err := strconv.Atoi(head); err != nil {
+				return sf, false
+			}
+			t = t.Elem()
+			continue
+		}
+
+		if t.Kind() != reflect.Struct {
+			return sf, false
+		}
+
+		f, ok := findField(t, head, aliasTag)
+		if !ok {
+			return sf, false
+		}
+
+		sf = f
+		t = f.Type
+	}
+
+	return sf, true
+}
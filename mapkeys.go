@@ -0,0 +1,111 @@
+package copperhead
+
+import (
+	"reflect"
+
+	"github.com/pkg/errors"
+)
+
+// WithMapKeyNormalizer normalizes the keys of string keyed map fields
+// whenever they are populated from configuration data or assigned, so
+// that strings.ToLower can be used to get predictable keys regardless
+// of the casing used in different sources. The normalizer must be
+// idempotent, as keys are normalized every time a source is applied.
+func WithMapKeyNormalizer(normalize func(key string) string) Option {
+	return func(c *Config) error {
+		c.mapKeys = normalize
+		return c.normalizeMapKeys("", c.obj)
+	}
+}
+
+// normalizeMapKeys normalizes the keys of all string keyed maps in
+// v. Keys that normalize to the same value cause an error.
+func (c *Config) normalizeMapKeys(name string, v reflect.Value) error {
+	if c.mapKeys == nil {
+		return nil
+	}
+
+	return c.normalizeMapKeysIn(name, v, visited{})
+}
+
+func (c *Config) normalizeMapKeysIn(name string, v reflect.Value, seen visited) error {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		if v.Kind() == reflect.Ptr && !seen.visit(v) {
+			return nil
+		}
+		return c.normalizeMapKeysIn(name, v.Elem(), seen)
+
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+			if f.PkgPath != "" {
+				continue
+			}
+
+			path := f.Name
+			if name != "" {
+				path = name + "." + f.Name
+			}
+
+			if err := c.normalizeMapKeysIn(path, v.Field(i), seen); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := c.normalizeMapKeysIn(name, v.Index(i), seen); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Map:
+		if v.Type().Key().Kind() == reflect.String {
+			if err := c.normalizeKeys(name, v); err != nil {
+				return err
+			}
+		}
+
+		for _, key := range v.MapKeys() {
+			if err := c.normalizeMapKeysIn(name, v.MapIndex(key), seen); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// normalizeKeys normalizes the keys of a map. Keys that already are
+// normalized have been set by earlier sources, so they are
+// overwritten by keys that normalize to the same value.
+func (c *Config) normalizeKeys(name string, m reflect.Value) error {
+	renamed := make(map[string]string)
+
+	for _, key := range m.MapKeys() {
+		normalized := c.mapKeys(key.String())
+		if normalized == key.String() {
+			continue
+		}
+
+		if other, ok := renamed[normalized]; ok {
+			return errors.Errorf(
+				"the keys %q and %q in %q conflict after normalization",
+				other, key.String(), name,
+			)
+		}
+		renamed[normalized] = key.String()
+
+		nk := reflect.New(key.Type()).Elem()
+		nk.SetString(normalized)
+
+		m.SetMapIndex(nk, m.MapIndex(key))
+		m.SetMapIndex(key, reflect.Value{})
+	}
+
+	return nil
+}
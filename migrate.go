@@ -0,0 +1,89 @@
+package copperhead
+
+import (
+	"github.com/pkg/errors"
+)
+
+// WithConfigMigrations upgrades older configuration documents before
+// they are unmarshalled, see Config.Migrations.
+func WithConfigMigrations(migrations map[int]func([]byte) ([]byte, error)) Option {
+	return func(c *Config) error {
+		return c.Migrations(migrations)
+	}
+}
+
+// Migrations sets the migrations that are applied to configuration
+// documents that are read after the call. The migrations are keyed
+// by the version that they upgrade from, so that the migration for 0
+// turns a version 0 document into a version 1 document, and the
+// current version is one higher than the highest key.
+//
+// The version of a document is read from its top-level "version"
+// key, and documents without one are treated as version 0. The
+// migrations from the version of the document up to the current
+// version are applied in order, and documents with a version newer
+// than the current one are errors.
+func (c *Config) Migrations(migrations map[int]func([]byte) ([]byte, error)) error {
+	current := 0
+	for from, fn := range migrations {
+		if from < 0 {
+			return errors.Errorf(
+				"invalid migration from version %d", from)
+		}
+		if fn == nil {
+			return errors.Errorf(
+				"the migration from version %d is nil", from)
+		}
+		if from+1 > current {
+			current = from + 1
+		}
+	}
+
+	for from := 0; from < current; from++ {
+		if _, ok := migrations[from]; !ok {
+			return errors.Errorf(
+				"missing migration from version %d", from)
+		}
+	}
+
+	c.migrations = migrations
+	c.currentVersion = current
+
+	return nil
+}
+
+// documentVersion is used to read the version of a document.
+type documentVersion struct {
+	Version int `json:"version" yaml:"version"`
+}
+
+// migrate applies the migrations from the version of the document up
+// to the current version.
+func (c *Config) migrate(name string, data []byte, unm Unmarshaler) ([]byte, error) {
+	var doc documentVersion
+	if err := unmarshalInto(name, data, unm, &doc); err != nil {
+		return nil, errors.Wrap(err,
+			"failed to read the configuration version")
+	}
+
+	if doc.Version > c.currentVersion || doc.Version < 0 {
+		return nil, errors.Errorf(
+			"unsupported configuration version %d, the current version is %d",
+			doc.Version, c.currentVersion)
+	}
+
+	for v := doc.Version; v < c.currentVersion; v++ {
+		migrated, err := c.migrations[v](data)
+		if err != nil {
+			return nil, errors.Wrapf(err,
+				"failed to migrate from version %d to %d", v, v+1)
+		}
+
+		c.logf("migrated configuration %q from version %d to %d",
+			name, v, v+1)
+
+		data = migrated
+	}
+
+	return data, nil
+}
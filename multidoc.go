@@ -0,0 +1,78 @@
+package copperhead
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"regexp"
+
+	"github.com/pkg/errors"
+)
+
+// WithMultiDocument enables multi-document loading, see
+// Config.MultiDocument.
+func WithMultiDocument() Option {
+	return func(c *Config) error {
+		c.MultiDocument()
+		return nil
+	}
+}
+
+// MultiDocument makes configuration data be treated as a stream of
+// documents that are unmarshaled in order, so that later documents
+// override earlier ones. Documents are either separated by "---"
+// lines, as in multi-document YAML, or are concatenated JSON values,
+// as in NDJSON.
+func (c *Config) MultiDocument() {
+	c.multiDoc = true
+}
+
+var documentSeparator = regexp.MustCompile(`(?m)^---[ \t]*(#.*)?\r?$`)
+
+// splitDocuments splits a stream of documents. Data that isn't a
+// YAML or JSON stream is returned as a single document. Empty
+// documents are kept so that the indexes of the documents match
+// their positions in the stream, except for the empty space before a
+// leading "---" line.
+func splitDocuments(data []byte) ([][]byte, error) {
+	var docs [][]byte
+
+	if documentSeparator.Match(data) {
+		for i, doc := range documentSeparator.Split(string(data), -1) {
+			if i == 0 && isEmptyDocument([]byte(doc)) {
+				continue
+			}
+			docs = append(docs, []byte(doc))
+		}
+		return docs, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for {
+		var raw json.RawMessage
+
+		err := dec.Decode(&raw)
+		if err == io.EOF {
+			break
+		}
+
+		// If we fail to decode the first document it's not a
+		// JSON stream.
+		if err != nil && len(docs) == 0 {
+			return [][]byte{data}, nil
+		}
+
+		if err != nil {
+			return nil, errors.Wrapf(err,
+				"malformed document at index %d", len(docs))
+		}
+
+		docs = append(docs, raw)
+	}
+
+	return docs, nil
+}
+
+func isEmptyDocument(doc []byte) bool {
+	return len(bytes.TrimSpace(doc)) == 0
+}
@@ -0,0 +1,155 @@
+package copperhead
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	normalizerMutex sync.RWMutex
+	normalizers     = map[string]func(string) string{
+		"trim":      strings.TrimSpace,
+		"lower":     strings.ToLower,
+		"upper":     strings.ToUpper,
+		"trimslash": func(s string) string { return strings.TrimRight(s, "/") },
+	}
+)
+
+// RegisterNormalizer registers a named normalizer that can be used
+// in the "normalize" tag option. Panics if the name has already been
+// registered, the built-in normalizers are "trim", "lower", "upper",
+// and "trimslash".
+func RegisterNormalizer(name string, fn func(string) string) {
+	if fn == nil {
+		panic("copperhead: RegisterNormalizer normalizer is nil")
+	}
+
+	normalizerMutex.Lock()
+	defer normalizerMutex.Unlock()
+
+	if _, dup := normalizers[name]; dup {
+		panic("copperhead: RegisterNormalizer called twice for " + name)
+	}
+
+	normalizers[name] = fn
+}
+
+// WithNormalization normalizes string fields after all the other
+// options have been applied, see Config.Normalize. Note that this
+// means that Require and Validate options check the values before
+// they're normalized, so a name of "   " passes Require and is then
+// trimmed to "". Use Normalize before the checks to have them see
+// the normalized values.
+func WithNormalization() Option {
+	return func(c *Config) error {
+		c.normalize = true
+		return nil
+	}
+}
+
+// Normalize normalizes string fields at its position among the
+// options, so that the options after it, like Require and Validate,
+// see the normalized values, see Config.Normalize:
+//
+//	err := copperhead.Configure(&conf,
+//		copperhead.WithConfigurationFile(
+//			"config.json", copperhead.FileRequired, nil),
+//		copperhead.Normalize(),
+//		copperhead.Require("Name"),
+//	)
+func Normalize() Option {
+	return func(c *Config) error {
+		return c.Normalize()
+	}
+}
+
+// Normalize applies the normalizers listed in the "normalize" tag
+// option of string and string slice fields, in order:
+//
+//	BaseURL string `copperhead:"normalize='trim,trimslash'"`
+//
+// Fields behind nil pointers are skipped.
+func (c *Config) Normalize() error {
+	if err := c.checkFrozen(); err != nil {
+		return err
+	}
+
+	var err error
+
+	walkFields(c.obj.Type(), func(path string, f reflect.StructField) {
+		names := c.fieldTag(f).Get("normalize")
+		if err != nil || names == "" {
+			return
+		}
+
+		fns, nErr := lookupNormalizers(names)
+		if nErr != nil {
+			err = errors.Wrapf(nErr, "invalid normalization of %q", path)
+			return
+		}
+
+		v, lErr := c.lookup(path)
+		if lErr != nil {
+			return
+		}
+
+		if nErr := normalizeValue(v, fns); nErr != nil {
+			err = errors.Wrapf(nErr, "failed to normalize %q", path)
+		}
+	})
+
+	return err
+}
+
+func lookupNormalizers(names string) ([]func(string) string, error) {
+	normalizerMutex.RLock()
+	defer normalizerMutex.RUnlock()
+
+	var fns []func(string) string
+
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+
+		fn, ok := normalizers[name]
+		if !ok {
+			return nil, errors.Errorf("unknown normalizer %q", name)
+		}
+
+		fns = append(fns, fn)
+	}
+
+	return fns, nil
+}
+
+func normalizeValue(v reflect.Value, fns []func(string) string) error {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		s := v.String()
+		for _, fn := range fns {
+			s = fn(s)
+		}
+		v.SetString(s)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := normalizeValue(v.Index(i), fns); err != nil {
+				return err
+			}
+		}
+	default:
+		return errors.Errorf(
+			"only strings can be normalized, not %q",
+			v.Type().String())
+	}
+
+	return nil
+}
@@ -0,0 +1,198 @@
+package copperhead
+
+import (
+	"encoding/json"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+func isNumber(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16,
+		reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16,
+		reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64,
+		reflect.Complex64, reflect.Complex128:
+		return true
+	}
+	return false
+}
+
+// assignNumber parses val according to the kind and bit size of
+// target. Integers can be written using exponents as long as the
+// resulting value is a whole number.
+func assignNumber(target reflect.Value, val string) error {
+	val = strings.TrimSpace(val)
+	bits := target.Type().Bits()
+	typeName := target.Kind().String()
+
+	switch target.Kind() {
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(val, bits)
+		if err != nil {
+			return numError(val, typeName, err)
+		}
+		target.SetFloat(f)
+
+	case reflect.Complex64, reflect.Complex128:
+		x, err := strconv.ParseComplex(val, bits)
+		if err != nil {
+			return numError(val, typeName, err)
+		}
+		target.SetComplex(x)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16,
+		reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(strings.TrimPrefix(val, "+"), 10, bits)
+		if isSyntaxError(err) {
+			var f float64
+			f, err = parseWholeFloat(val)
+			if err == nil && (f < 0 || f >= math.Ldexp(1, bits)) {
+				err = strconv.ErrRange
+			}
+			u = uint64(f)
+		}
+		if err != nil {
+			return numError(val, typeName, err)
+		}
+		target.SetUint(u)
+
+	default:
+		i, err := strconv.ParseInt(val, 10, bits)
+		if isSyntaxError(err) {
+			var f float64
+			f, err = parseWholeFloat(val)
+			if err == nil && (f < -math.Ldexp(1, bits-1) ||
+				f >= math.Ldexp(1, bits-1)) {
+				err = strconv.ErrRange
+			}
+			i = int64(f)
+		}
+		if err != nil {
+			return numError(val, typeName, err)
+		}
+		target.SetInt(i)
+	}
+
+	return nil
+}
+
+var (
+	jsonNumberType = reflect.TypeOf(json.Number(""))
+	bigIntType     = reflect.TypeOf(big.Int{})
+	bigFloatType   = reflect.TypeOf(big.Float{})
+	bigRatType     = reflect.TypeOf(big.Rat{})
+)
+
+// assignJSONNumber assigns val as-is to a json.Number after checking
+// that it's a valid JSON number.
+func assignJSONNumber(target reflect.Value, val string) error {
+	val = strings.TrimSpace(val)
+
+	if val == "" || !(val[0] == '-' || (val[0] >= '0' && val[0] <= '9')) ||
+		!json.Valid([]byte(val)) {
+		return errors.Errorf("invalid number %q", val)
+	}
+
+	target.SetString(val)
+
+	return nil
+}
+
+// assignBig parses val into big.Int, big.Float, and big.Rat
+// targets, ok is false if target is of another type. Integers are
+// parsed with base prefixes, as in "0x1f". Floats keep their
+// precision if it has been set, otherwise the precision is chosen so
+// that all the digits of val are kept.
+func assignBig(target reflect.Value, val string) (ok bool, err error) {
+	val = strings.TrimSpace(val)
+
+	switch target.Type() {
+	case bigIntType:
+		i := target.Addr().Interface().(*big.Int)
+		if _, ok := i.SetString(val, 0); !ok {
+			return true, errors.Errorf("invalid big.Int value %q", val)
+		}
+
+	case bigFloatType:
+		f := target.Addr().Interface().(*big.Float)
+		if f.Prec() == 0 {
+			f.SetPrec(decimalPrec(val))
+		}
+		if _, _, err := f.Parse(val, 0); err != nil {
+			return true, errors.Errorf("invalid big.Float value %q", val)
+		}
+
+	case bigRatType:
+		r := target.Addr().Interface().(*big.Rat)
+		if _, ok := r.SetString(val); !ok {
+			return true, errors.Errorf("invalid big.Rat value %q", val)
+		}
+
+	default:
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// decimalPrec returns the number of mantissa bits needed to keep all
+// the digits of a number, but at least 64.
+func decimalPrec(val string) uint {
+	var digits int
+	for _, r := range val {
+		if r >= '0' && r <= '9' {
+			digits++
+		}
+	}
+
+	prec := uint(math.Ceil(float64(digits) * math.Log2(10)))
+	if prec < 64 {
+		prec = 64
+	}
+
+	return prec
+}
+
+// parseWholeFloat parses a floating point number that must be a
+// whole number, like "1e3".
+func parseWholeFloat(val string) (float64, error) {
+	f, err := strconv.ParseFloat(val, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	if f != math.Trunc(f) || math.IsInf(f, 0) {
+		return 0, errors.New("not a whole number")
+	}
+
+	return f, nil
+}
+
+func isSyntaxError(err error) bool {
+	ne, ok := err.(*strconv.NumError)
+	return ok && ne.Err == strconv.ErrSyntax
+}
+
+func numError(val, typeName string, err error) error {
+	if ne, ok := err.(*strconv.NumError); ok {
+		err = ne.Err
+	}
+
+	switch err {
+	case strconv.ErrRange:
+		return errors.Errorf(
+			"value %q out of range for %s", val, typeName)
+	case strconv.ErrSyntax:
+		return errors.Errorf(
+			"invalid %s value %q", typeName, val)
+	}
+
+	return errors.Wrapf(err, "invalid %s value %q", typeName, val)
+}
@@ -0,0 +1,41 @@
+package copperhead
+
+// WithOptionalWhenEmpty skips the required checks when the whole
+// configuration is empty, see Config.OptionalWhenEmpty.
+func WithOptionalWhenEmpty() Option {
+	return func(c *Config) error {
+		c.OptionalWhenEmpty()
+		return nil
+	}
+}
+
+// OptionalWhenEmpty makes the configuration optional as a whole, so
+// that it can be either fully configured or entirely omitted. While
+// no source other than defaults has set any field the Require checks
+// pass, and fields with the "required" tag option aren't reported as
+// missing. Values that are set in code before loading, and the values
+// of "default" tag options, don't count. As soon as any field is set
+// by a source all checks apply as usual. The required checks of
+// options given before this one have already run.
+func (c *Config) OptionalWhenEmpty() {
+	c.optionalWhenEmpty = true
+}
+
+// skipRequired checks if the required checks should be skipped
+// because the configuration is optional and no source has populated
+// it.
+func (c *Config) skipRequired() bool {
+	if !c.optionalWhenEmpty {
+		return false
+	}
+
+	for _, sources := range c.provenance {
+		for _, src := range sources {
+			if src.Type != SourceDefault {
+				return false
+			}
+		}
+	}
+
+	return true
+}
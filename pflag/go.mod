@@ -0,0 +1,14 @@
+module github.com/Sydsvenskan/copperhead/pflag
+
+go 1.18
+
+require (
+	github.com/Sydsvenskan/copperhead v0.0.0-20261016164258-50a7753edb8c
+	github.com/pkg/errors v0.8.0
+	github.com/spf13/pflag v1.0.10
+)
+
+require (
+	github.com/kr/text v0.1.0 // indirect
+	gopkg.in/yaml.v2 v2.2.1 // indirect
+)
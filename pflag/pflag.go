@@ -0,0 +1,77 @@
+// Package pflag binds spf13/pflag command line flags to copperhead
+// configuration fields, so that flags can override configuration
+// from other sources:
+//
+//	fs := pflag.NewFlagSet("app", pflag.ExitOnError)
+//	fs.Int("birdie-value", 0, "the value of the bird")
+//	fs.Parse(os.Args[1:])
+//
+//	err := copperhead.Configure(&conf,
+//		copperhead.WithConfigurationFile(
+//			"config.json", copperhead.FileOptional, nil),
+//		cpflag.WithFlags(fs),
+//	)
+//
+// The package is a module of its own, so that copperhead doesn't
+// depend on pflag unless it's used.
+package pflag
+
+import (
+	"strings"
+
+	"github.com/Sydsvenskan/copperhead"
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+)
+
+// WithFlags binds the flags of fs to configuration fields, see
+// BindFlags.
+func WithFlags(fs *pflag.FlagSet) copperhead.Option {
+	return func(c *copperhead.Config) error {
+		return BindFlags(c, fs)
+	}
+}
+
+// BindFlags assigns the values of changed flags to the fields that
+// they match. A flag matches a field if the flag name is the dotted
+// path of the field, case insensitively, with dashes in place of
+// dots, so "birdie-value" matches "Birdie.Value". Camel case words
+// can be separated by dashes as well, so "birdie-complex-env" matches
+// "Birdie.ComplexEnv". Flags that match no field are ignored.
+func BindFlags(c *copperhead.Config, fs *pflag.FlagSet) error {
+	paths := make(map[string]string)
+	for _, f := range c.Fields() {
+		paths[flagName(f.Path)] = f.Path
+		paths[strings.ToLower(strings.Replace(
+			copperhead.UpperSnake(f.Path), "_", "-", -1))] = f.Path
+	}
+
+	var err error
+
+	fs.VisitAll(func(f *pflag.Flag) {
+		if err != nil || !f.Changed {
+			return
+		}
+
+		path, ok := paths[strings.ToLower(f.Name)]
+		if !ok {
+			return
+		}
+
+		value := f.Value.String()
+		if sv, ok := f.Value.(pflag.SliceValue); ok {
+			value = strings.Join(sv.GetSlice(), ",")
+		}
+
+		if sErr := c.Set(path, value); sErr != nil {
+			err = errors.Wrapf(sErr,
+				"failed to assign the flag %q", f.Name)
+		}
+	})
+
+	return err
+}
+
+func flagName(path string) string {
+	return strings.ToLower(strings.Replace(path, ".", "-", -1))
+}
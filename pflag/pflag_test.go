@@ -0,0 +1,74 @@
+package pflag_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Sydsvenskan/copperhead"
+	cpflag "github.com/Sydsvenskan/copperhead/pflag"
+	"github.com/spf13/pflag"
+)
+
+type conf struct {
+	Name   string
+	Port   int
+	Tags   []string
+	Birdie struct {
+		ComplexEnv string
+		Timeout    copperhead.Duration
+	}
+}
+
+func TestBindFlags(t *testing.T) {
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	fs.String("name", "flag-default", "")
+	fs.Int("port", 0, "")
+	fs.StringSlice("tags", nil, "")
+	fs.String("birdie-complex-env", "", "")
+	fs.Duration("birdie-timeout", 0, "")
+	fs.Bool("verbose", false, "")
+
+	err := fs.Parse([]string{
+		"--port=9090", "--tags=a,b", "--birdie-complex-env=flag",
+		"--birdie-timeout=5s", "--verbose",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var c conf
+	err = copperhead.Configure(&c,
+		copperhead.WithConfigurationData([]byte(`{
+			"Name": "file",
+			"Port": 80
+		}`), nil),
+		cpflag.WithFlags(fs),
+	)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if c.Name != "file" {
+		t.Errorf("expected unchanged flags to be ignored, got %q", c.Name)
+	}
+
+	if c.Port != 9090 || len(c.Tags) != 2 || c.Tags[1] != "b" ||
+		c.Birdie.ComplexEnv != "flag" || c.Birdie.Timeout.Duration != 5*time.Second {
+		t.Errorf("unexpected config %#v", c)
+	}
+}
+
+func TestBindFlagsError(t *testing.T) {
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	fs.String("port", "", "")
+
+	if err := fs.Parse([]string{"--port=many"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var c conf
+	err := copperhead.Configure(&c, cpflag.WithFlags(fs))
+	if err == nil {
+		t.Error("expected invalid flag value to fail")
+	}
+}
@@ -0,0 +1,108 @@
+package copperhead
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// WithPreflightCheck checks that the required files and directories
+// of all the options that follow it exist and are readable before
+// they are applied. Every missing or unreadable file is reported in a
+// single error.
+//
+// The check applies the following options to a copy of the
+// configuration where stdin and remote sources are skipped. Files and
+// directories that pass the check are loaded into the copy, so that
+// options that depend on earlier values, like FileTemplate, check
+// the right files. Loggers, validators, and the handlers
+// for missing files, missing environment variables, and deprecation
+// warnings aren't called by the check. Options that do I/O of their
+// own should check Config.Preflight() and return early.
+func WithPreflightCheck() Option {
+	return func(c *Config) error {
+		c.preflightRequested = true
+		return nil
+	}
+}
+
+// Preflight returns true if the options are being applied as part of
+// a preflight check.
+func (c *Config) Preflight() bool {
+	return c.preflight != nil
+}
+
+// runPreflight applies the options to a copy of the configuration in
+// preflight mode.
+func (c *Config) runPreflight(opts []Option) error {
+	clone := c.clone()
+	clone.preflight = &preflightState{}
+
+	// Errors are ignored as they will resurface when the options
+	// are applied for real. Options that depend on data from files
+	// are likely to fail here.
+	for _, opt := range opts {
+		_ = opt(clone)
+	}
+
+	if problems := clone.preflight.problems; len(problems) > 0 {
+		return errors.Errorf("preflight check failed: %s",
+			strings.Join(problems, ", "))
+	}
+
+	return nil
+}
+
+type preflightState struct {
+	problems []string
+}
+
+// checkFile verifies that a file can be opened, returns true if it
+// can be read.
+func (p *preflightState) checkFile(filename string, mode FileMode) bool {
+	f, err := os.Open(filename)
+	switch {
+	case os.IsNotExist(err) && mode.allowsMissing():
+	case os.IsNotExist(err):
+		p.problems = append(p.problems, "missing configuration file "+
+			strconv.Quote(filename))
+	case err != nil:
+		p.problems = append(p.problems, err.Error())
+	default:
+		f.Close()
+		return true
+	}
+	return false
+}
+
+// checkDir verifies that a directory can be read, returns true if it
+// can be read.
+func (p *preflightState) checkDir(dir string, mode FileMode) bool {
+	_, err := ioutil.ReadDir(dir)
+	switch {
+	case os.IsNotExist(err) && mode.allowsMissing():
+	case os.IsNotExist(err):
+		p.problems = append(p.problems, "missing configuration directory "+
+			strconv.Quote(dir))
+	case err != nil:
+		p.problems = append(p.problems, err.Error())
+	default:
+		return true
+	}
+	return false
+}
+
+// checkGlob verifies that a required glob pattern has matches.
+func (p *preflightState) checkGlob(pattern string, mode FileMode) bool {
+	matches, _ := filepath.Glob(pattern)
+	if len(matches) == 0 && !mode.allowsMissing() {
+		p.problems = append(p.problems, "no configuration files matching "+
+			strconv.Quote(pattern))
+		return false
+	}
+	return true
+}
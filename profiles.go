@@ -0,0 +1,78 @@
+package copperhead
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultProfile is the name of the section that is applied before
+// the active profile.
+const DefaultProfile = "default"
+
+// WithProfiles treats configuration documents as a set of profiles,
+// see Config.Profiles.
+func WithProfiles(active string) Option {
+	return func(c *Config) error {
+		return c.Profiles(active)
+	}
+}
+
+// WithProfileFallback allows the active profile to be missing from
+// documents, so that only the default profile is applied.
+func WithProfileFallback() Option {
+	return func(c *Config) error {
+		c.profileFallback = true
+		return nil
+	}
+}
+
+// Profiles treats configuration documents that are read after the
+// call as sets of profiles, where the top-level keys are profile
+// names, as in:
+//
+//	default:
+//	  port: 8080
+//	prod:
+//	  port: 80
+//
+// The "default" profile is merged onto the configuration first, and
+// then the active profile. Documents that lack a section for the
+// active profile are errors unless WithProfileFallback is used.
+func (c *Config) Profiles(active string) error {
+	if active == "" {
+		return errors.New("the active profile cannot be empty")
+	}
+	c.profile = active
+	return nil
+}
+
+func (c *Config) unmarshalProfiles(name string, data []byte, unm Unmarshaler) error {
+	var sections map[string]interface{}
+	if err := unmarshalInto(name, data, unm, &sections); err != nil {
+		return err
+	}
+
+	if _, ok := sections[c.profile]; !ok && !c.profileFallback {
+		return errors.Errorf("unknown profile %q", c.profile)
+	}
+
+	for _, profile := range []string{DefaultProfile, c.profile} {
+		if _, ok := sections[profile]; !ok {
+			continue
+		}
+
+		pointer := "/" + strings.NewReplacer("~", "~0", "/", "~1").Replace(profile)
+		err := c.unmarshalAt(name, data, pointer, []string{profile}, unm)
+		if err != nil {
+			return errors.Wrapf(err,
+				"failed to apply the profile %q", profile)
+		}
+
+		if profile == c.profile {
+			break
+		}
+	}
+
+	return nil
+}
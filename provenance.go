@@ -0,0 +1,161 @@
+package copperhead
+
+import (
+	"reflect"
+)
+
+// SourceType identifies the kind of source a value came from.
+type SourceType string
+
+// The source types
+const (
+	SourceEnv     SourceType = "env"
+	SourceDefault SourceType = "default"
+	SourceFile    SourceType = "file"
+	SourceData    SourceType = "data"
+	SourceStdin   SourceType = "stdin"
+	SourceRemote  SourceType = "remote"
+	SourceMerge   SourceType = "merge"
+	SourceSet     SourceType = "set"
+	SourceBind    SourceType = "bind"
+)
+
+// Source describes where a configuration value came from.
+type Source struct {
+	Type SourceType
+	// Name is the name of the source, like the environment
+	// variable name, filename, or URL.
+	Name string
+}
+
+// String returns the source type and name, separated by a colon.
+func (s Source) String() string {
+	if s.Name == "" {
+		return string(s.Type)
+	}
+	return string(s.Type) + ":" + s.Name
+}
+
+// Provenance returns the source that last set each field, keyed by
+// dotted path. Only fields that have been set are included. Values
+// from configuration data are attributed to the source for every key
+// that the data contains, even if it sets a field to the value that
+// it already has. Data that the unmarshaler can't decode into a copy
+// of the configuration struct with pointer fields, which is used to
+// find the keys, is only attributed to the fields that it changes.
+func (c *Config) Provenance() map[string]Source {
+	p := make(map[string]Source, len(c.provenance))
+	for path, sources := range c.provenance {
+		p[path] = sources[len(sources)-1]
+	}
+	return p
+}
+
+// ProvenanceHistory returns all the sources that have set each
+// field in the order that they were applied, keyed by dotted path.
+func (c *Config) ProvenanceHistory() map[string][]Source {
+	p := make(map[string][]Source, len(c.provenance))
+	for path, sources := range c.provenance {
+		p[path] = append([]Source(nil), sources...)
+	}
+	return p
+}
+
+// record adds src as a source of the field at path.
+func (c *Config) record(path string, src Source) {
+	if c.provenance == nil {
+		c.provenance = make(map[string][]Source)
+	}
+	c.provenance[path] = append(c.provenance[path], src)
+
+	c.warnDeprecated(path)
+}
+
+// track calls fn and records src as the source of the fields that it
+// changes.
+func (c *Config) track(src Source, fn func() error) error {
+	return c.trackChanges(src, nil, fn)
+}
+
+// trackAssigned calls fn to decode configuration data that assigns
+// the fields at paths, and records src as their source. Only data
+// that sets fields that src isn't allowed to set needs a copy of the
+// configuration, so that the fields can be reverted.
+func (c *Config) trackAssigned(src Source, paths []string, fn func() error) error {
+	if err := c.checkFrozen(); err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		if ok, _ := c.CheckSource(path, src); !ok {
+			return c.trackChanges(src, paths, fn)
+		}
+	}
+
+	if err := fn(); err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		c.record(path, src)
+	}
+
+	return nil
+}
+
+// trackChanges calls fn and records src as the source of the fields
+// that it changes, and of the assigned fields.
+func (c *Config) trackChanges(src Source, assigned []string, fn func() error) error {
+	if err := c.checkFrozen(); err != nil {
+		return err
+	}
+
+	before := deepCopy(c.obj)
+
+	err := fn()
+
+	isAssigned := make(map[string]bool, len(assigned))
+	for _, path := range assigned {
+		isAssigned[path] = true
+	}
+
+	walkFields(c.obj.Type(), func(path string, f reflect.StructField) {
+		oldVal := valueAt(before, path, f.Type, c.aliasTag())
+		newVal := valueAt(c.obj, path, f.Type, c.aliasTag())
+
+		changed := !reflect.DeepEqual(oldVal, newVal)
+		if !changed && !isAssigned[path] {
+			return
+		}
+
+		ok, sErr := c.CheckSource(path, src)
+		if !ok {
+			// Values that a source isn't allowed to set are
+			// only violations if they change the field.
+			if !changed {
+				return
+			}
+
+			c.revertField(before, path)
+
+			if err == nil {
+				err = sErr
+			}
+			return
+		}
+
+		c.record(path, src)
+	})
+
+	return err
+}
+
+// valueAt gets the value at a path, unreachable values are treated as
+// zero values.
+func valueAt(root reflect.Value, path string, t reflect.Type, aliasTag string) interface{} {
+	v, err := walk(root, path, false, aliasTag)
+	if err != nil || !v.CanInterface() {
+		return reflect.Zero(t).Interface()
+	}
+	return v.Interface()
+}
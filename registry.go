@@ -0,0 +1,92 @@
+package copperhead
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	implMutex    sync.RWMutex
+	implRegistry = make(map[reflect.Type]map[string]func() interface{})
+)
+
+// RegisterInterfaceImpl registers a named implementation of an
+// interface. Configuration values assigned to fields of the
+// interface type will select the implementation by name and assign
+// the value returned by factory. Panics if ifaceType isn't an
+// interface type or if the name has already been registered.
+//
+//	copperhead.RegisterInterfaceImpl(
+//		reflect.TypeOf((*Backend)(nil)).Elem(), "redis",
+//		func() interface{} { return &RedisBackend{} },
+//	)
+func RegisterInterfaceImpl(
+	ifaceType reflect.Type, name string, factory func() interface{},
+) {
+	if ifaceType == nil || ifaceType.Kind() != reflect.Interface {
+		panic("copperhead: RegisterInterfaceImpl requires an interface type")
+	}
+
+	if factory == nil {
+		panic("copperhead: RegisterInterfaceImpl factory is nil")
+	}
+
+	implMutex.Lock()
+	defer implMutex.Unlock()
+
+	impls, ok := implRegistry[ifaceType]
+	if !ok {
+		impls = make(map[string]func() interface{})
+		implRegistry[ifaceType] = impls
+	}
+
+	if _, dup := impls[name]; dup {
+		panic("copperhead: RegisterInterfaceImpl called twice for " +
+			ifaceType.String() + " " + name)
+	}
+
+	impls[name] = factory
+}
+
+// assignImpl assigns a registered implementation to an interface
+// value, ok will be false if no implementations have been
+// registered for the interface type.
+func assignImpl(target reflect.Value, name string) (ok bool, err error) {
+	implMutex.RLock()
+	impls, ok := implRegistry[target.Type()]
+	factory := impls[name]
+	implMutex.RUnlock()
+
+	if !ok {
+		return false, nil
+	}
+
+	if factory == nil {
+		names := make([]string, 0, len(impls))
+		for n := range impls {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+
+		return true, errors.Errorf(
+			"unknown %s implementation %q, expected one of: %s",
+			target.Type().String(), name, strings.Join(names, ", "),
+		)
+	}
+
+	impl := reflect.ValueOf(factory())
+	if !impl.IsValid() || !impl.Type().Implements(target.Type()) {
+		return true, errors.Errorf(
+			"the %q factory didn't return a %s",
+			name, target.Type().String(),
+		)
+	}
+
+	target.Set(impl)
+
+	return true, nil
+}
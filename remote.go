@@ -0,0 +1,105 @@
+package copperhead
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// RemoteOption configures remote configuration loading.
+type RemoteOption func(r *remote)
+
+type remote struct {
+	client *http.Client
+}
+
+// WithHTTPClient sets the HTTP client that is used to fetch
+// remote configuration, defaults to http.DefaultClient.
+func WithHTTPClient(client *http.Client) RemoteOption {
+	return func(r *remote) {
+		r.client = client
+	}
+}
+
+// StatusError is returned when a remote configuration source
+// responds with a non-2xx status code.
+type StatusError struct {
+	URL        string
+	StatusCode int
+}
+
+// Error implements the error interface.
+func (se StatusError) Error() string {
+	return fmt.Sprintf(
+		"unexpected status %d from %q",
+		se.StatusCode, se.URL,
+	)
+}
+
+// WithConfigurationURL reads configuration from a URL.
+func WithConfigurationURL(
+	ctx context.Context, rawURL string, unm Unmarshaler,
+	opts ...RemoteOption,
+) Option {
+	return func(c *Config) error {
+		return c.Remote(ctx, rawURL, unm, opts...)
+	}
+}
+
+// Remote reads configuration from a URL using a GET request.
+func (c *Config) Remote(
+	ctx context.Context, rawURL string, unm Unmarshaler,
+	opts ...RemoteOption,
+) error {
+	if c.preflight != nil {
+		return nil
+	}
+
+	r := remote{
+		client: http.DefaultClient,
+	}
+
+	for _, opt := range opts {
+		opt(&r)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return errors.Wrap(err,
+			"failed to create configuration request")
+	}
+
+	res, err := r.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return errors.Wrapf(err,
+			"failed to fetch configuration from %q", rawURL)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return errors.Wrap(StatusError{
+			URL:        rawURL,
+			StatusCode: res.StatusCode,
+		}, "failed to fetch configuration")
+	}
+
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return errors.Wrapf(err,
+			"failed to read configuration from %q", rawURL)
+	}
+
+	c.logf("fetched configuration from %q", rawURL)
+
+	if unm == nil {
+		unm = c.jsonUnmarshaler()
+	}
+
+	err = c.unmarshalNamed(Source{Type: SourceRemote, Name: rawURL},
+		rawURL, data, unm)
+	return errors.Wrapf(err,
+		"failed to unmarshal configuration from %q", rawURL)
+}
@@ -0,0 +1,186 @@
+package copperhead
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+
+	"github.com/pkg/errors"
+)
+
+// Redacted is the value that redacted string fields are set to.
+const Redacted = "[redacted]"
+
+// Save writes the configuration to a file, marshal defaults to
+// indented JSON. The file is written to a temporary file that then
+// replaces filename, so that a failed save doesn't leave a partially
+// written file behind. New files are created with the mode 0600,
+// existing files keep their mode.
+func (c *Config) Save(filename string, marshal func(v interface{}) ([]byte, error)) error {
+	return c.save(filename, marshal, c.obj)
+}
+
+// SaveRedacted writes the configuration to a file just like Save,
+// but fields tagged as secret are redacted. String fields are set
+// to Redacted and other fields are set to their zero value.
+func (c *Config) SaveRedacted(filename string, marshal func(v interface{}) ([]byte, error)) error {
+	return c.save(filename, marshal, redactedCopy(c.obj, c.tagKey, visited{}))
+}
+
+func (c *Config) save(
+	filename string, marshal func(v interface{}) ([]byte, error),
+	v reflect.Value,
+) error {
+	if marshal == nil {
+		marshal = marshalIndentJSON
+	}
+
+	data, err := marshal(v.Addr().Interface())
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal configuration")
+	}
+
+	return errors.Wrapf(writeFileAtomic(filename, data),
+		"failed to write configuration file %q", filename)
+}
+
+func marshalIndentJSON(v interface{}) ([]byte, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+func writeFileAtomic(filename string, data []byte) error {
+	mode := os.FileMode(0600)
+	if info, err := os.Stat(filename); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmp, err := ioutil.TempFile(
+		filepath.Dir(filename), "."+filepath.Base(filename)+".tmp")
+	if err != nil {
+		return err
+	}
+
+	// Clean up the temporary file if we fail before the rename.
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), filename)
+}
+
+// redactedCopy returns an addressable copy of v with all fields
+// tagged as secret redacted. Structs, pointers, slices, arrays, maps,
+// and interface values are copied as they are traversed, so that v
+// is left untouched, and pointers that are reached more than once are
+// only copied once. Fields of unexported embedded structs can't be
+// assigned and are left as they are.
+func redactedCopy(v reflect.Value, key string, seen visited) reflect.Value {
+	cp := reflect.New(v.Type()).Elem()
+	cp.Set(redactValue(v, key, seen))
+	return cp
+}
+
+func redactValue(v reflect.Value, key string, seen visited) reflect.Value {
+	switch v.Kind() {
+	case reflect.Struct:
+		cp := reflect.New(v.Type()).Elem()
+		cp.Set(v)
+
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+			if f.PkgPath != "" {
+				continue
+			}
+
+			if getFieldTag(key, f).Has("secret") {
+				cp.Field(i).Set(redacted(f.Type))
+				continue
+			}
+
+			cp.Field(i).Set(redactValue(v.Field(i), key, seen))
+		}
+
+		return cp
+
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		if cp, ok := seen.copyOf(v); ok {
+			return cp
+		}
+		cp := reflect.New(v.Type().Elem())
+		seen.setCopy(v, cp)
+		cp.Elem().Set(redactValue(v.Elem(), key, seen))
+		return cp
+
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		cp := reflect.New(v.Type()).Elem()
+		cp.Set(redactValue(v.Elem(), key, seen))
+		return cp
+
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		cp := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			cp.Index(i).Set(redactValue(v.Index(i), key, seen))
+		}
+		return cp
+
+	case reflect.Array:
+		cp := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			cp.Index(i).Set(redactValue(v.Index(i), key, seen))
+		}
+		return cp
+
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		cp := reflect.MakeMapWithSize(v.Type(), v.Len())
+		for _, k := range v.MapKeys() {
+			cp.SetMapIndex(k, redactValue(v.MapIndex(k), key, seen))
+		}
+		return cp
+	}
+
+	return v
+}
+
+// redacted returns the redacted value of a secret of type t, strings
+// are set to Redacted and other types to their zero value.
+func redacted(t reflect.Type) reflect.Value {
+	v := reflect.New(t).Elem()
+	if t.Kind() == reflect.String {
+		v.SetString(Redacted)
+	}
+	return v
+}
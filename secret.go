@@ -0,0 +1,79 @@
+package copperhead
+
+import (
+	"encoding"
+	"encoding/json"
+)
+
+// secretMask is shown in place of the values of Secret.
+const secretMask = "****"
+
+// Secret holds a value that shouldn't be leaked through logging or
+// serialization. Formatting and marshaling a Secret always gives
+// "****", use Value() to get the actual value. Secrets are loaded
+// like the type they hold, string and []byte values are assigned
+// as-is.
+type Secret[T any] struct {
+	value T
+}
+
+// NewSecret creates a Secret holding value.
+func NewSecret[T any](value T) Secret[T] {
+	return Secret[T]{value: value}
+}
+
+// Value returns the secret value.
+func (s Secret[T]) Value() T {
+	return s.value
+}
+
+// String implements fmt.Stringer.
+func (s Secret[T]) String() string {
+	return secretMask
+}
+
+// GoString implements fmt.GoStringer.
+func (s Secret[T]) GoString() string {
+	return secretMask
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s Secret[T]) MarshalText() ([]byte, error) {
+	return []byte(secretMask), nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (s Secret[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(secretMask)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *Secret[T]) UnmarshalText(text []byte) error {
+	switch v := any(&s.value).(type) {
+	case *string:
+		*v = string(text)
+		return nil
+	case *[]byte:
+		*v = append([]byte{}, text...)
+		return nil
+	case encoding.TextUnmarshaler:
+		return v.UnmarshalText(text)
+	}
+
+	return json.Unmarshal(text, &s.value)
+}
+
+// UnmarshalJSON implements json.Unmarshaler. String and []byte
+// secrets are read from JSON strings.
+func (s *Secret[T]) UnmarshalJSON(data []byte) error {
+	switch any(&s.value).(type) {
+	case *string, *[]byte, encoding.TextUnmarshaler:
+		var str string
+		if err := json.Unmarshal(data, &str); err != nil {
+			return err
+		}
+		return s.UnmarshalText([]byte(str))
+	}
+
+	return json.Unmarshal(data, &s.value)
+}
@@ -0,0 +1,72 @@
+package copperhead
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// WithIgnoredSourceViolations makes values from sources that a
+// field doesn't allow be ignored instead of failing, see
+// Config.CheckSource.
+func WithIgnoredSourceViolations() Option {
+	return func(c *Config) error {
+		c.ignoreSource = true
+		return nil
+	}
+}
+
+// CheckSource checks if a value from src may be assigned to the
+// field at path. Fields restrict their sources using the "source" tag
+// option, which lists the allowed source types:
+//
+//	ClusterID string `copperhead:"source=file"`
+//	Token     string `copperhead:"source='env,set'"`
+//
+// Values from other sources fail with an error, or are skipped when
+// WithIgnoredSourceViolations is used. Fields without the option
+// accept values from all sources.
+func (c *Config) CheckSource(path string, src Source) (ok bool, err error) {
+	f, found := fieldByPath(c.obj.Type(), path, c.aliasTag())
+	if !found {
+		return true, nil
+	}
+
+	allowed := c.fieldTag(f).Get("source")
+	if allowed == "" {
+		return true, nil
+	}
+
+	for _, t := range strings.Split(allowed, ",") {
+		if SourceType(strings.TrimSpace(t)) == src.Type {
+			return true, nil
+		}
+	}
+
+	if c.ignoreSource {
+		c.logf("ignoring the value of %s from %s, only %s is allowed",
+			path, src, allowed)
+		return false, nil
+	}
+
+	return false, errors.Errorf(
+		"%q cannot be set from %s, only from %s",
+		path, src, allowed)
+}
+
+// revertField restores the value of the field at path from before.
+func (c *Config) revertField(before reflect.Value, path string) {
+	v, err := walk(c.obj, path, false, c.aliasTag())
+	if err != nil || !v.CanSet() {
+		return
+	}
+
+	old, err := walk(before, path, false, c.aliasTag())
+	if err != nil {
+		v.Set(reflect.Zero(v.Type()))
+		return
+	}
+
+	v.Set(old)
+}
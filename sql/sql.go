@@ -0,0 +1,68 @@
+// Package sql loads configuration from a database table of keys and
+// values, like:
+//
+//	CREATE TABLE settings (key TEXT PRIMARY KEY, value TEXT);
+//
+// The keys are dotted field paths, as in "Database.Port", and the
+// values are assigned in the same way as environment variables.
+package sql
+
+import (
+	"context"
+	dbsql "database/sql"
+
+	"github.com/Sydsvenskan/copperhead"
+	"github.com/pkg/errors"
+)
+
+// WithSQL assigns the key-value rows returned by a query to fields,
+// see Load. The query is run with the context of the configuration.
+func WithSQL(db *dbsql.DB, query string, args ...interface{}) copperhead.Option {
+	return func(c *copperhead.Config) error {
+		return Load(c.Context(), c, db, query, args...)
+	}
+}
+
+// Load runs a query that returns key and value columns, and assigns
+// each value to the field with the dotted path in key. The query can
+// be parameterized using args:
+//
+//	err := sql.Load(ctx, c, db,
+//		"SELECT key, value FROM settings WHERE service = $1", "myapp")
+//
+// NULL values and keys that don't resolve to a field are errors.
+func Load(
+	ctx context.Context, c *copperhead.Config,
+	db *dbsql.DB, query string, args ...interface{},
+) error {
+	if c.Preflight() {
+		return nil
+	}
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return errors.Wrap(err, "failed to query configuration")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var key string
+		var value dbsql.NullString
+
+		if err := rows.Scan(&key, &value); err != nil {
+			return errors.Wrap(err,
+				"failed to read configuration row, expected key and value columns")
+		}
+
+		if !value.Valid {
+			return errors.Errorf("the value of %q is NULL", key)
+		}
+
+		if err := c.Set(key, value.String); err != nil {
+			return errors.Wrapf(err,
+				"failed to assign the key %q", key)
+		}
+	}
+
+	return errors.Wrap(rows.Err(), "failed to read configuration rows")
+}
@@ -0,0 +1,126 @@
+package sql_test
+
+import (
+	"context"
+	dbsql "database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/Sydsvenskan/copperhead"
+	"github.com/Sydsvenskan/copperhead/sql"
+)
+
+// fakeDriver returns the rows of the table named by the first query
+// argument.
+type fakeDriver map[string][][2]interface{}
+
+func (d fakeDriver) Open(name string) (driver.Conn, error) {
+	return fakeConn{tables: d}, nil
+}
+
+type fakeConn struct {
+	tables fakeDriver
+}
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return fakeStmt(c), nil
+}
+
+func (c fakeConn) Close() error              { return nil }
+func (c fakeConn) Begin() (driver.Tx, error) { return nil, driver.ErrSkip }
+
+type fakeStmt fakeConn
+
+func (s fakeStmt) Close() error  { return nil }
+func (s fakeStmt) NumInput() int { return 1 }
+
+func (s fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, driver.ErrSkip
+}
+
+func (s fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return &fakeRows{rows: s.tables[args[0].(string)]}, nil
+}
+
+type fakeRows struct {
+	rows [][2]interface{}
+}
+
+func (r *fakeRows) Columns() []string { return []string{"key", "value"} }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	dest[0], dest[1] = r.rows[0][0], r.rows[0][1]
+	r.rows = r.rows[1:]
+	return nil
+}
+
+func init() {
+	dbsql.Register("copperhead-fake", fakeDriver{
+		"myapp": {
+			{"Name", "app"},
+			{"Database.Port", "5432"},
+		},
+		"null": {
+			{"Name", nil},
+		},
+		"unknown": {
+			{"Database.Hostname", "db"},
+		},
+	})
+}
+
+type appConf struct {
+	Name     string
+	Database struct {
+		Host string
+		Port int
+	}
+}
+
+func TestSQL(t *testing.T) {
+	db, err := dbsql.Open("copperhead-fake", "")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer db.Close()
+
+	var conf appConf
+	err = copperhead.Configure(&conf,
+		sql.WithSQL(db, "SELECT key, value FROM settings WHERE service = ?", "myapp"),
+	)
+	if err != nil {
+		t.Fatal("failed to load settings: " + err.Error())
+	}
+
+	if conf.Name != "app" || conf.Database.Port != 5432 {
+		t.Errorf("unexpected configuration %#v", conf)
+	}
+
+	cases := map[string]string{
+		"null":    `"Name" is NULL`,
+		"unknown": `"Database.Hostname"`,
+	}
+
+	for service, expected := range cases {
+		err := sql.Load(context.Background(), mustConfig(t, &conf), db,
+			"SELECT key, value FROM settings WHERE service = ?", service)
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected %q to fail with %q, got: %v",
+				service, expected, err)
+		}
+	}
+}
+
+func mustConfig(t *testing.T, conf interface{}) *copperhead.Config {
+	c, err := copperhead.New(conf)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	return c
+}
@@ -0,0 +1,71 @@
+package copperhead
+
+import (
+	"context"
+)
+
+// ApplyStream applies configuration data received on updates, as
+// with Data, until updates is closed or the context is cancelled.
+// Every update is applied to a copy of the configuration, which is
+// normalized and has derived values computed as when loading, and
+// the configuration is only changed if the whole update succeeds.
+//
+// The result of every update is sent on the returned channel, nil
+// for updates that were applied, and the channel is closed when
+// ApplyStream stops. The configuration is changed from another
+// goroutine, so reads of the configuration must be synchronized with
+// the results, f.ex. by only reading values after receiving nil.
+func (c *Config) ApplyStream(
+	ctx context.Context, updates <-chan []byte, unm Unmarshaler,
+) <-chan error {
+	results := make(chan error)
+
+	go func() {
+		defer close(results)
+
+		for {
+			var data []byte
+			var ok bool
+
+			select {
+			case <-ctx.Done():
+				return
+			case data, ok = <-updates:
+				if !ok {
+					return
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case results <- c.applyUpdate(data, unm):
+			}
+		}
+	}()
+
+	return results
+}
+
+// applyUpdate applies configuration data to a copy of the
+// configuration and copies it back if it succeeds.
+func (c *Config) applyUpdate(data []byte, unm Unmarshaler) error {
+	if err := c.checkFrozen(); err != nil {
+		return err
+	}
+
+	clone := c.clone()
+
+	if err := clone.Data(data, unm); err != nil {
+		return err
+	}
+
+	if err := clone.finish(); err != nil {
+		return err
+	}
+
+	c.obj.Set(clone.obj)
+	c.provenance = clone.provenance
+
+	return nil
+}
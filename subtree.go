@@ -0,0 +1,189 @@
+package copperhead
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// WithConfigurationFileAt reads the configuration from the part of a
+// file selected by a JSON pointer, see Config.FileAt.
+func WithConfigurationFileAt(
+	filename, pointer string, mode FileMode, unm Unmarshaler,
+) Option {
+	return func(c *Config) error {
+		return c.FileAt(filename, pointer, mode, unm)
+	}
+}
+
+// WithConfigurationFilePrefix reads the configuration from a
+// top-level key of a file, see Config.FilePrefix.
+func WithConfigurationFilePrefix(
+	filename, prefix string, mode FileMode, unm Unmarshaler,
+) Option {
+	return func(c *Config) error {
+		return c.FilePrefix(filename, prefix, mode, unm)
+	}
+}
+
+// PointerError is returned when a JSON pointer doesn't resolve to a
+// value in a configuration document.
+type PointerError struct {
+	Pointer string
+	Reason  string
+}
+
+func (e *PointerError) Error() string {
+	return fmt.Sprintf("JSON pointer %q doesn't resolve: %s",
+		e.Pointer, e.Reason)
+}
+
+// FileAt reads the configuration from the subtree of a file that is
+// selected by a JSON pointer (RFC 6901), so that "/services/myapp"
+// loads the "myapp" section of a shared file. An empty pointer loads
+// the whole file. A pointer that doesn't resolve gives a
+// *PointerError, while a subtree that doesn't fit the configuration
+// gives an unmarshalling error.
+func (c *Config) FileAt(filename, pointer string, mode FileMode, unm Unmarshaler) error {
+	if pointer == "" {
+		return c.File(filename, mode, unm)
+	}
+
+	if unm == nil {
+		unm = c.jsonUnmarshaler()
+	}
+
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return err
+	}
+
+	data, ok, err := c.readConfigFile(filename, mode)
+	if err != nil || !ok {
+		return err
+	}
+
+	err = c.track(Source{Type: SourceFile, Name: filename}, func() error {
+		return c.unmarshalAt(filename, data, pointer, tokens, unm)
+	})
+	return errors.Wrapf(err,
+		"failed to unmarshal configuration file %q",
+		filename,
+	)
+}
+
+// FilePrefix reads the configuration from the value of the top-level
+// key prefix of a file, so that the prefix "myapp" loads the "myapp"
+// section of a file shared by many services. It's a shorthand for
+// FileAt with a single key pointer.
+func (c *Config) FilePrefix(filename, prefix string, mode FileMode, unm Unmarshaler) error {
+	if prefix == "" {
+		return errors.New("the prefix cannot be empty")
+	}
+
+	pointer := "/" + strings.NewReplacer("~", "~0", "/", "~1").Replace(prefix)
+
+	return c.FileAt(filename, pointer, mode, unm)
+}
+
+func parsePointer(pointer string) ([]string, error) {
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, errors.Errorf(
+			"invalid JSON pointer %q, must start with a slash",
+			pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(t)
+	}
+
+	return tokens, nil
+}
+
+func (c *Config) unmarshalAt(
+	name string, data []byte, pointer string, tokens []string, unm Unmarshaler,
+) error {
+	var doc interface{}
+	if err := unmarshalInto(name, data, unm, &doc); err != nil {
+		return err
+	}
+
+	value, err := resolvePointer(doc, pointer, tokens)
+	if err != nil {
+		return err
+	}
+
+	if c.obj.Kind() == reflect.Struct && !isObject(value) {
+		return errors.Errorf(
+			"the value at %q is a %T, a %q needs an object",
+			pointer, value, c.obj.Type().String())
+	}
+
+	// The selected value is encoded as JSON, which also is valid
+	// YAML, and decoded into the configuration using the same
+	// unmarshaler, so that keys are matched like in a whole file.
+	sub, err := json.Marshal(stringKeys(value))
+	if err != nil {
+		return errors.Wrapf(err,
+			"failed to encode the value at %q", pointer)
+	}
+
+	if err := unmarshalInto(name, sub, unm, c.obj.Addr().Interface()); err != nil {
+		return errors.Wrapf(err,
+			"the value at %q doesn't match the configuration", pointer)
+	}
+
+	return c.normalizeMapKeys("", c.obj)
+}
+
+func resolvePointer(
+	doc interface{}, pointer string, tokens []string,
+) (interface{}, error) {
+	node := doc
+
+	for i, t := range tokens {
+		var ok bool
+
+		switch n := node.(type) {
+		case map[string]interface{}:
+			node, ok = n[t]
+		case map[interface{}]interface{}:
+			node, ok = n[t]
+		case []interface{}:
+			idx, err := strconv.Atoi(t)
+			ok = err == nil && idx >= 0 && idx < len(n)
+			if ok {
+				node = n[idx]
+			}
+		default:
+			return nil, &PointerError{
+				Pointer: pointer,
+				Reason: fmt.Sprintf("%q is a %T, not an object or array",
+					"/"+strings.Join(tokens[:i], "/"), node),
+			}
+		}
+
+		if !ok {
+			return nil, &PointerError{
+				Pointer: pointer,
+				Reason: fmt.Sprintf("%q has no %q",
+					"/"+strings.Join(tokens[:i], "/"), t),
+			}
+		}
+	}
+
+	return node, nil
+}
+
+func isObject(v interface{}) bool {
+	switch v.(type) {
+	case map[string]interface{}, map[interface{}]interface{}:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,119 @@
+package copperhead
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultTagKey is the struct tag key used for field options unless
+// another key is set using WithTagKey.
+const DefaultTagKey = "copperhead"
+
+// WithTagKey sets the struct tag key that field options are read
+// from, as in `cfg:"required,secret"` for the key "cfg". The default
+// key is DefaultTagKey. All tag options, like required, secret, and
+// desc, are read using the key, so it should be the first option.
+// Aliases are declared in their own "alias" tag, see WithAliases.
+func WithTagKey(key string) Option {
+	return func(c *Config) error {
+		if key == "" {
+			return errors.New("the tag key cannot be empty")
+		}
+		c.tagKey = key
+		return nil
+	}
+}
+
+// fieldTag holds the parsed options of a copperhead struct tag.
+//
+// The tag is a comma separated list of options that are either
+// flags, as in "required", or have a value, as in "env=APP_PORT".
+// Values can be quoted using single quotes to contain commas:
+//
+//	Port int `copperhead:"env=APP_PORT,default=8080,required"`
+//	Name string `copperhead:"desc='The name, as shown to users'"`
+type fieldTag map[string]string
+
+// Has checks if the tag has an option.
+func (ft fieldTag) Has(option string) bool {
+	_, ok := ft[option]
+	return ok
+}
+
+// Get returns the value of an option.
+func (ft fieldTag) Get(option string) string {
+	return ft[option]
+}
+
+// fieldTag parses the field options of a struct field.
+func (c *Config) fieldTag(f reflect.StructField) fieldTag {
+	return getFieldTag(c.tagKey, f)
+}
+
+// aliasTag returns the tag key to look up aliases with, or an empty
+// string if aliases aren't enabled.
+func (c *Config) aliasTag() string {
+	if !c.aliases {
+		return ""
+	}
+	return aliasTagKey
+}
+
+// getFieldTag parses the options in the tag with the given key of a
+// struct field.
+func getFieldTag(key string, f reflect.StructField) fieldTag {
+	return parseTag(f.Tag.Get(key))
+}
+
+func parseTag(tag string) fieldTag {
+	ft := make(fieldTag)
+
+	for len(tag) > 0 {
+		var item string
+
+		item, tag = nextTagItem(tag)
+
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		eq := strings.Index(item, "=")
+		if eq == -1 {
+			ft[item] = ""
+			continue
+		}
+
+		name := strings.TrimSpace(item[:eq])
+		value := strings.TrimSpace(item[eq+1:])
+
+		if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+			value = value[1 : len(value)-1]
+		}
+
+		ft[name] = value
+	}
+
+	return ft
+}
+
+// nextTagItem splits off the next comma separated item, taking
+// single quoted values into account.
+func nextTagItem(tag string) (item, rest string) {
+	quoted := false
+
+	for i := 0; i < len(tag); i++ {
+		switch tag[i] {
+		case '\'':
+			quoted = !quoted
+		case ',':
+			if !quoted {
+				return tag[:i], tag[i+1:]
+			}
+		}
+	}
+
+	return tag, ""
+}
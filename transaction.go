@@ -0,0 +1,38 @@
+package copperhead
+
+import (
+	"reflect"
+)
+
+// WithTransactional makes loading all-or-nothing: the options that
+// follow it are applied to a copy of the configuration, and the copy
+// is only written back when all of them, and the derived values,
+// succeed. If loading fails the configuration is left untouched,
+// which makes it safe to load into a live configuration, f.ex. on
+// reload. Options that precede WithTransactional are applied
+// directly, so it should be the first option.
+func WithTransactional() Option {
+	return func(c *Config) error {
+		if c.target.IsValid() {
+			return nil
+		}
+
+		c.target = c.obj
+		c.obj = reflect.New(c.obj.Type()).Elem()
+		c.obj.Set(deepCopy(c.target))
+
+		return nil
+	}
+}
+
+// commit writes a transactional copy of the configuration back to
+// the original value.
+func (c *Config) commit() {
+	if !c.target.IsValid() {
+		return
+	}
+
+	c.target.Set(c.obj)
+	c.obj = c.target
+	c.target = reflect.Value{}
+}
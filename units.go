@@ -0,0 +1,149 @@
+package copperhead
+
+import (
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+// WithUnitConversion enables the "unit" tag option for numeric
+// fields, see Config.UnitConversion.
+func WithUnitConversion() Option {
+	return func(c *Config) error {
+		c.UnitConversion()
+		return nil
+	}
+}
+
+// UnitConversion enables conversion of durations and sizes for
+// numeric fields with the "unit" tag option when assigning values
+// from the environment or using Set. Plain numbers are assigned
+// as-is:
+//
+//	TimeoutSeconds int   `copperhead:"unit=seconds"` // "1m" -> 60
+//	MaxBytes       int64 `copperhead:"unit=bytes"`   // "10MB" -> 10000000
+//
+// Duration units are nanoseconds, microseconds, milliseconds,
+// seconds, minutes, and hours. Sizes use the unit bytes and accept
+// both decimal (KB, MB, GB, TB) and binary (KiB, MiB, GiB, TiB)
+// suffixes.
+func (c *Config) UnitConversion() {
+	c.units = true
+}
+
+var durationUnits = map[string]time.Duration{
+	"nanoseconds":  time.Nanosecond,
+	"microseconds": time.Microsecond,
+	"milliseconds": time.Millisecond,
+	"seconds":      time.Second,
+	"minutes":      time.Minute,
+	"hours":        time.Hour,
+}
+
+var sizeSuffixes = map[string]int64{
+	"":    1,
+	"b":   1,
+	"kb":  1000,
+	"mb":  1000 * 1000,
+	"gb":  1000 * 1000 * 1000,
+	"tb":  1000 * 1000 * 1000 * 1000,
+	"kib": 1 << 10,
+	"mib": 1 << 20,
+	"gib": 1 << 30,
+	"tib": 1 << 40,
+}
+
+// assignField assigns a value to the field at path, converting it
+// according to the "unit" tag option if unit conversion is enabled,
+// and normalizing map keys if a normalizer has been set. Percent
+// fields with the "fraction" tag option are parsed with bare numbers
+// as fractions.
+func (c *Config) assignField(path string, target reflect.Value, val string) error {
+	if baseType(target.Type()) == percentType {
+		f, ok := fieldByPath(c.obj.Type(), path, c.aliasTag())
+		if ok && c.fieldTag(f).Has("fraction") {
+			p, err := ensureZero(path, target)
+			if err != nil {
+				return err
+			}
+			return p.Addr().Interface().(*Percent).parse([]byte(val), true)
+		}
+	}
+
+	if c.units && isNumber(baseType(target.Type()).Kind()) {
+		f, ok := fieldByPath(c.obj.Type(), path, c.aliasTag())
+		if unit := c.fieldTag(f).Get("unit"); ok && unit != "" {
+			converted, err := convertUnit(val, unit)
+			if err != nil {
+				return err
+			}
+			val = converted
+		}
+	}
+
+	if err := c.assign(target, val); err != nil {
+		return err
+	}
+
+	return c.normalizeMapKeys(path, target)
+}
+
+// convertUnit converts a duration or size to a count of unit.
+func convertUnit(val, unit string) (string, error) {
+	val = strings.TrimSpace(val)
+
+	// Plain numbers are already expressed in the unit.
+	if _, err := strconv.ParseFloat(val, 64); err == nil {
+		return val, nil
+	}
+
+	var count *big.Rat
+
+	if d, ok := durationUnits[unit]; ok {
+		pd, err := time.ParseDuration(val)
+		if err != nil {
+			return "", errors.Errorf("invalid duration %q", val)
+		}
+		count = big.NewRat(int64(pd), int64(d))
+	} else if unit == "bytes" {
+		size, err := parseSize(val)
+		if err != nil {
+			return "", err
+		}
+		count = size
+	} else {
+		return "", errors.Errorf("unknown unit %q", unit)
+	}
+
+	if count.IsInt() {
+		return count.Num().String(), nil
+	}
+
+	f, _ := count.Float64()
+	return strconv.FormatFloat(f, 'f', -1, 64), nil
+}
+
+// parseSize parses a size like "10MB" or "1.5GiB" into bytes.
+func parseSize(val string) (*big.Rat, error) {
+	i := strings.IndexFunc(val, unicode.IsLetter)
+	if i == -1 {
+		i = len(val)
+	}
+
+	multiplier, ok := sizeSuffixes[strings.ToLower(strings.TrimSpace(val[i:]))]
+	if !ok {
+		return nil, errors.Errorf("unknown size suffix in %q", val)
+	}
+
+	n, ok := new(big.Rat).SetString(strings.TrimSpace(val[:i]))
+	if !ok {
+		return nil, errors.Errorf("invalid size %q", val)
+	}
+
+	return n.Mul(n, big.NewRat(multiplier, 1)), nil
+}
@@ -1,8 +1,17 @@
 package copperhead
 
 import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"math"
 	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/pkg/errors"
 )
 
 // URL is an TextUnmarshaler-aware URL
@@ -20,6 +29,16 @@ func (u *URL) UnmarshalText(text []byte) error {
 	return nil
 }
 
+// MarshalText implements encoding.TextMarshaler.
+func (u URL) MarshalText() ([]byte, error) {
+	return []byte(u.String()), nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (u URL) MarshalJSON() ([]byte, error) {
+	return json.Marshal(u.String())
+}
+
 // MustParseURL is a helper function for setting configuration
 // defaults. Panics if the passed url is invalid.
 func MustParseURL(rawURL string) *URL {
@@ -47,6 +66,172 @@ func (t *Time) UnmarshalText(text []byte) error {
 	return nil
 }
 
+// UnmarshalJSON implements json.Unmarshaler. Accepts either a
+// RFC3339 string or a number of seconds since the Unix epoch. A JSON
+// null leaves the time unchanged.
+func (t *Time) UnmarshalJSON(data []byte) error {
+	if isJSONNull(data) {
+		return nil
+	}
+
+	var str string
+	if err := json.Unmarshal(data, &str); err == nil {
+		return t.UnmarshalText([]byte(str))
+	}
+
+	var num json.Number
+	if err := json.Unmarshal(data, &num); err != nil {
+		return errors.New("time must be a string or a number")
+	}
+
+	if sec, err := num.Int64(); err == nil {
+		t.Time = time.Unix(sec, 0).UTC()
+		return nil
+	}
+
+	f, err := num.Float64()
+	if err != nil {
+		return errors.Wrapf(err, "invalid Unix time %q", num)
+	}
+
+	sec, frac := math.Modf(f)
+	t.Time = time.Unix(int64(sec), int64(math.Round(frac*1e9))).UTC()
+
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (t Time) MarshalText() ([]byte, error) {
+	return []byte(t.Format(time.RFC3339Nano)), nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (t Time) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.Format(time.RFC3339Nano))
+}
+
+// LocalTime is a TextUnmarshaler-aware Time that parses values
+// using a set of layouts, interpreting values without a time zone in
+// DefaultLocation. Use TimeInLocation to create a LocalTime to populate.
+type LocalTime struct {
+	time.Time
+
+	// DefaultLocation is used for values without zone
+	// information, defaults to time.Local.
+	DefaultLocation *time.Location
+	// Layouts are tried in order, defaults to time.RFC3339.
+	Layouts []string
+}
+
+// TimeInLocation creates a LocalTime that parses values in the
+// location using the layouts. Assign it to a configuration field
+// before loading:
+//
+//	conf.Start = copperhead.TimeInLocation(loc, "2006-01-02 15:04")
+func TimeInLocation(loc *time.Location, layouts ...string) LocalTime {
+	return LocalTime{
+		DefaultLocation: loc,
+		Layouts:         layouts,
+	}
+}
+
+// noZone is used to detect values that were parsed without zone
+// information, as no real location has a one second offset.
+var noZone = time.FixedZone("", 1)
+
+// UnmarshalText implements encoding.TextUnmarshaler. Local times
+// that don't exist, or are ambiguous, because of daylight saving
+// time transitions result in an error.
+func (t *LocalTime) UnmarshalText(text []byte) error {
+	loc := t.DefaultLocation
+	if loc == nil {
+		loc = time.Local
+	}
+
+	layouts := t.Layouts
+	if len(layouts) == 0 {
+		layouts = []string{time.RFC3339}
+	}
+
+	var (
+		pt  time.Time
+		err error
+	)
+	for _, layout := range layouts {
+		pt, err = time.ParseInLocation(layout, string(text), noZone)
+		if err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	if pt.Location() != noZone {
+		t.Time = pt
+		return nil
+	}
+
+	lt := time.Date(pt.Year(), pt.Month(), pt.Day(),
+		pt.Hour(), pt.Minute(), pt.Second(), pt.Nanosecond(), loc)
+
+	if !sameWallClock(lt, pt) {
+		return errors.Errorf(
+			"the time %q doesn't exist in %q", text, loc.String())
+	}
+
+	// Check for other instants with the same wall clock time, DST
+	// transitions are at most a couple of hours.
+	for d := -2 * time.Hour; d <= 2*time.Hour; d += 15 * time.Minute {
+		if d != 0 && sameWallClock(lt.Add(d), pt) {
+			return errors.Errorf(
+				"the time %q is ambiguous in %q", text, loc.String())
+		}
+	}
+
+	t.Time = lt
+
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler. A JSON null leaves the
+// time unchanged.
+func (t *LocalTime) UnmarshalJSON(data []byte) error {
+	if isJSONNull(data) {
+		return nil
+	}
+
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return err
+	}
+	return t.UnmarshalText([]byte(str))
+}
+
+// MarshalText implements encoding.TextMarshaler, the time is
+// formatted using the first layout.
+func (t LocalTime) MarshalText() ([]byte, error) {
+	layout := time.RFC3339Nano
+	if len(t.Layouts) > 0 {
+		layout = t.Layouts[0]
+	}
+	return []byte(t.Format(layout)), nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (t LocalTime) MarshalJSON() ([]byte, error) {
+	text, _ := t.MarshalText()
+	return json.Marshal(string(text))
+}
+
+func sameWallClock(a, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd &&
+		a.Hour() == b.Hour() && a.Minute() == b.Minute() &&
+		a.Second() == b.Second() && a.Nanosecond() == b.Nanosecond()
+}
+
 // Duration is an TextUnmarshaler-aware Duration
 type Duration struct {
 	time.Duration
@@ -63,3 +248,380 @@ func (d *Duration) UnmarshalText(text []byte) error {
 
 	return nil
 }
+
+// UnmarshalJSON implements json.Unmarshaler. Accepts either a
+// duration string, like "10s", or a number of nanoseconds. A JSON
+// null leaves the duration unchanged.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	if isJSONNull(data) {
+		return nil
+	}
+
+	var str string
+	if err := json.Unmarshal(data, &str); err == nil {
+		return d.UnmarshalText([]byte(str))
+	}
+
+	var num json.Number
+	if err := json.Unmarshal(data, &num); err != nil {
+		return errors.New("duration must be a string or a number")
+	}
+
+	ns, err := num.Int64()
+	if err != nil {
+		return errors.Errorf(
+			"invalid duration %q, expected whole nanoseconds", num)
+	}
+
+	d.Duration = time.Duration(ns)
+
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// StringSlice is a TextUnmarshaler-aware string slice that accepts
+// comma separated text as well as JSON arrays.
+type StringSlice []string
+
+// UnmarshalText implements encoding.TextUnmarshaler. The text is
+// split on commas and each value is trimmed of whitespace.
+func (s *StringSlice) UnmarshalText(text []byte) error {
+	values := []string{}
+
+	if len(strings.TrimSpace(string(text))) > 0 {
+		for _, v := range strings.Split(string(text), ",") {
+			values = append(values, strings.TrimSpace(v))
+		}
+	}
+
+	*s = values
+
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler. Accepts either an
+// array of strings or a single comma separated string. A JSON null
+// leaves the slice unchanged.
+func (s *StringSlice) UnmarshalJSON(data []byte) error {
+	if isJSONNull(data) {
+		return nil
+	}
+
+	var str string
+	if err := json.Unmarshal(data, &str); err == nil {
+		return s.UnmarshalText([]byte(str))
+	}
+
+	values := []string{}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+
+	if values == nil {
+		values = []string{}
+	}
+
+	*s = values
+
+	return nil
+}
+
+// Append adds values to the slice.
+func (s *StringSlice) Append(values ...string) {
+	*s = append(*s, values...)
+}
+
+// Contains checks if the slice contains value.
+func (s StringSlice) Contains(value string) bool {
+	for _, v := range s {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Email is a TextUnmarshaler-aware email address that validates
+// the address format and normalizes the domain to lower case.
+type Email string
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (e *Email) UnmarshalText(text []byte) error {
+	addr := strings.TrimSpace(string(text))
+
+	at := strings.LastIndex(addr, "@")
+	if at < 1 || at == len(addr)-1 {
+		return errors.Errorf("invalid email address %q", addr)
+	}
+
+	local, domain := addr[:at], addr[at+1:]
+
+	if len(local) > 64 || !validEmailLocal(local) {
+		return errors.Errorf(
+			"invalid local part in email address %q", addr)
+	}
+
+	host, err := normalizeHostname(domain)
+	if err != nil || !strings.Contains(host, ".") {
+		return errors.Errorf(
+			"invalid domain in email address %q", addr)
+	}
+
+	*e = Email(local + "@" + host)
+
+	return nil
+}
+
+// String returns the email address.
+func (e Email) String() string {
+	return string(e)
+}
+
+// validEmailLocal checks that a local part is a dot-atom as
+// described in RFC 5322.
+func validEmailLocal(local string) bool {
+	const special = "!#$%&'*+-/=?^_`{|}~"
+
+	for _, atom := range strings.Split(local, ".") {
+		if atom == "" {
+			return false
+		}
+
+		for _, r := range atom {
+			if !isAlnum(r) && !strings.ContainsRune(special, r) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// Hostname is a TextUnmarshaler-aware hostname that validates the
+// name according to RFC 1123 and normalizes it to lower case.
+type Hostname string
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (h *Hostname) UnmarshalText(text []byte) error {
+	host, err := normalizeHostname(strings.TrimSpace(string(text)))
+	if err != nil {
+		return err
+	}
+
+	*h = Hostname(host)
+
+	return nil
+}
+
+// String returns the hostname.
+func (h Hostname) String() string {
+	return string(h)
+}
+
+func normalizeHostname(name string) (string, error) {
+	host := strings.ToLower(strings.TrimSuffix(name, "."))
+
+	if host == "" || len(host) > 253 {
+		return "", errors.Errorf("invalid hostname %q", name)
+	}
+
+	for _, label := range strings.Split(host, ".") {
+		if label == "" || len(label) > 63 ||
+			label[0] == '-' || label[len(label)-1] == '-' {
+			return "", errors.Errorf("invalid hostname %q", name)
+		}
+
+		for _, r := range label {
+			if !isAlnum(r) && r != '-' {
+				return "", errors.Errorf(
+					"invalid hostname %q", name)
+			}
+		}
+	}
+
+	return host, nil
+}
+
+func isAlnum(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') ||
+		(r >= '0' && r <= '9')
+}
+
+// JSONRaw is a raw JSON value that captures configuration subtrees
+// as-is, it's also TextUnmarshaler-aware so that JSON can be passed
+// through the environment.
+type JSONRaw json.RawMessage
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (r *JSONRaw) UnmarshalText(text []byte) error {
+	if !json.Valid(text) {
+		return errors.New("invalid JSON value")
+	}
+
+	*r = append((*r)[0:0], text...)
+
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (r *JSONRaw) UnmarshalJSON(data []byte) error {
+	return r.UnmarshalText(data)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (r JSONRaw) MarshalJSON() ([]byte, error) {
+	if len(r) == 0 {
+		return []byte("null"), nil
+	}
+	return json.RawMessage(r).MarshalJSON()
+}
+
+// Unmarshal decodes the raw JSON into v.
+func (r JSONRaw) Unmarshal(v interface{}) error {
+	return json.Unmarshal(r, v)
+}
+
+// Percent is a TextUnmarshaler-aware percentage in the range
+// 0-100. It accepts values like "42" and "42%". Fields with the
+// "fraction" tag option treat bare numbers as fractions when
+// assigned from the environment or using Set, so that "0.42" means
+// 42%:
+//
+//	Sampling Percent `copperhead:"fraction"`
+type Percent float64
+
+var percentType = reflect.TypeOf(Percent(0))
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (p *Percent) UnmarshalText(text []byte) error {
+	return p.parse(text, false)
+}
+
+// parse parses a percentage, bare numbers are fractions if
+// fractions is true.
+func (p *Percent) parse(text []byte, fractions bool) error {
+	str := strings.TrimSpace(string(text))
+
+	value := strings.TrimSpace(strings.TrimSuffix(str, "%"))
+	isPercent := len(value) != len(str)
+
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(f) {
+		return errors.Errorf("invalid percentage %q", str)
+	}
+
+	if !isPercent && fractions {
+		f *= 100
+	}
+
+	if f < 0 || f > 100 {
+		return errors.Errorf(
+			"percentage %q is out of range 0-100", str)
+	}
+
+	*p = Percent(f)
+
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler. Accepts either a
+// number or a string. A JSON null leaves the percentage unchanged.
+func (p *Percent) UnmarshalJSON(data []byte) error {
+	if isJSONNull(data) {
+		return nil
+	}
+
+	var str string
+	if err := json.Unmarshal(data, &str); err == nil {
+		return p.UnmarshalText([]byte(str))
+	}
+
+	var num json.Number
+	if err := json.Unmarshal(data, &num); err != nil {
+		return err
+	}
+
+	return p.UnmarshalText([]byte(num))
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (p Percent) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// String returns the percentage formatted with a "%" suffix.
+func (p Percent) String() string {
+	return strconv.FormatFloat(float64(p), 'f', -1, 64) + "%"
+}
+
+// Fraction returns the percentage as a fraction in the range 0-1.
+func (p Percent) Fraction() float64 {
+	return float64(p) / 100
+}
+
+// FileOrBytes is a TextUnmarshaler-aware byte slice that is either
+// given as a literal value, or as a filename prefixed with "@", as in
+// "@/etc/tls/key.pem". A literal value starting with "@" can be given
+// using "@@".
+type FileOrBytes []byte
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (b *FileOrBytes) UnmarshalText(text []byte) error {
+	switch {
+	case bytes.HasPrefix(text, []byte("@@")):
+		*b = append(FileOrBytes{}, text[1:]...)
+	case bytes.HasPrefix(text, []byte("@")):
+		filename := string(text[1:])
+
+		data, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read %q", filename)
+		}
+
+		*b = data
+	default:
+		*b = append(FileOrBytes{}, text...)
+	}
+
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler, the value must be a
+// string. A JSON null leaves the value unchanged.
+func (b *FileOrBytes) UnmarshalJSON(data []byte) error {
+	if isJSONNull(data) {
+		return nil
+	}
+
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return err
+	}
+	return b.UnmarshalText([]byte(str))
+}
+
+// MarshalText implements encoding.TextMarshaler. The bytes are
+// written as a literal value, so a value that was read from a file
+// is saved with the contents of the file. Values that start with "@"
+// are escaped as "@@" so that they are read back as literals.
+func (b FileOrBytes) MarshalText() ([]byte, error) {
+	if bytes.HasPrefix(b, []byte("@")) {
+		return append([]byte("@"), b...), nil
+	}
+	return append([]byte(nil), b...), nil
+}
+
+// isJSONNull checks if data is the JSON null literal, which, like for
+// the standard types, should leave a value unchanged.
+func isJSONNull(data []byte) bool {
+	return bytes.Equal(bytes.TrimSpace(data), []byte("null"))
+}
@@ -0,0 +1,123 @@
+package copperhead
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Validate verifies the configuration, see Config.Validate.
+func Validate() Option {
+	return func(c *Config) error {
+		return c.Validate()
+	}
+}
+
+// WithValidator adds a validator that is run by Config.Validate.
+// Return a *ValidationError from the validator to report problems
+// with specific fields.
+func WithValidator(validator func(c *Config) error) Option {
+	return func(c *Config) error {
+		c.validators = append(c.validators, validator)
+		return nil
+	}
+}
+
+// ValidationError is returned by Validate and reports the problems
+// with each field.
+type ValidationError struct {
+	// Fields maps dotted field paths to the problems with the
+	// field. Problems that aren't tied to a field use an empty
+	// path.
+	Fields map[string][]string
+}
+
+// Add adds a problem with a field.
+func (e *ValidationError) Add(path, problem string) {
+	if e.Fields == nil {
+		e.Fields = make(map[string][]string)
+	}
+	e.Fields[path] = append(e.Fields[path], problem)
+}
+
+func (e *ValidationError) Error() string {
+	paths := make([]string, 0, len(e.Fields))
+	for path := range e.Fields {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var problems []string
+	for _, path := range paths {
+		msg := strings.Join(e.Fields[path], ", ")
+		if path != "" {
+			msg = strconv.Quote(path) + " " + msg
+		}
+		problems = append(problems, msg)
+	}
+
+	return "invalid configuration: " + strings.Join(problems, "; ")
+}
+
+// MissingRequired returns the paths of the fields with the
+// "required" tag option that aren't set, using the same rules as
+// Require. Fields behind nil pointers are missing.
+func (c *Config) MissingRequired() []string {
+	if c.skipRequired() {
+		return nil
+	}
+
+	var missing []string
+
+	walkFields(c.obj.Type(), func(path string, f reflect.StructField) {
+		if !c.fieldTag(f).Has("required") {
+			return
+		}
+
+		v, err := c.lookup(path)
+		if err != nil || checkRequired(path, v) != nil {
+			missing = append(missing, path)
+		}
+	})
+
+	return missing
+}
+
+// Validate runs all validations and reports every problem in a
+// single *ValidationError. Fields with the "required" tag option must
+// be set, see Require, and validators added using WithValidator are
+// run.
+func (c *Config) Validate() error {
+	verr := &ValidationError{}
+
+	for _, path := range c.MissingRequired() {
+		verr.Add(path, "is required")
+	}
+
+	for _, validator := range c.validators {
+		if c.preflight != nil {
+			break
+		}
+
+		err := validator(c)
+
+		switch e := err.(type) {
+		case nil:
+		case *ValidationError:
+			for path, problems := range e.Fields {
+				for _, p := range problems {
+					verr.Add(path, p)
+				}
+			}
+		default:
+			verr.Add("", err.Error())
+		}
+	}
+
+	if len(verr.Fields) > 0 {
+		return verr
+	}
+
+	return nil
+}
@@ -0,0 +1,13 @@
+package copperhead
+
+import (
+	yaml "gopkg.in/yaml.v2"
+)
+
+// YAML is an Unmarshaler for YAML configuration.
+var YAML = UnmarshalerFunc(yaml.Unmarshal)
+
+// YAMLMarshal marshals configuration as YAML, for use with Save.
+func YAMLMarshal(v interface{}) ([]byte, error) {
+	return yaml.Marshal(v)
+}